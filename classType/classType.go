@@ -5,4 +5,5 @@ type ClassType int
 const (
   NONE ClassType = iota
   CLASS
+  SUBCLASS
 ) 
\ No newline at end of file
@@ -9,16 +9,34 @@ import (
 type Environment struct {
   Enclosing *Environment
   values map[string]any
+  constants map[string]bool
   name string
 }
 
 func MakeEnvironment(parent *Environment, n string) Environment {
-  a := Environment{parent, make(map[string]any), n}
+  a := Environment{parent, make(map[string]any), make(map[string]bool), n}
   return a
 }
 
+// ConstantError is returned when a scope already holds a constant by the
+// name being defined. Callers report it at the name's token.
+type ConstantError struct {
+  Name string
+}
+func (e ConstantError) Error() string {
+  return fmt.Sprintf("Can't redefine constant '%s'.", e.Name)
+}
+
 func Define(e *Environment, name string, value any) error {
+  if e.constants[name] {return ConstantError{name}}
+  e.values[name] = value
+  return nil
+}
+
+func DefineConstant(e *Environment, name string, value any) error {
+  if e.constants[name] {return ConstantError{name}}
   e.values[name] = value
+  e.constants[name] = true
   return nil
 }
 
@@ -54,6 +72,9 @@ func Get(e *Environment, name token.Token) (any, error) {
 func Assign(e *Environment, name token.Token, value any) error {
   _, ok := e.values[name.Lexeme]
   if ok {
+    if e.constants[name.Lexeme] {
+      return loxError.RuntimeError{name, fmt.Sprintf("Can't assign to constant '%s'.", name.Lexeme)}
+    }
     e.values[name.Lexeme] = value
     return nil
   }
@@ -0,0 +1,293 @@
+package interpret
+
+import (
+	"fmt"
+	"lox/loxError"
+	"lox/token"
+	"lox/typeKind"
+)
+
+// The analyzer rides along with the resolver: every scope the resolver opens
+// carries a map of what is known about each variable's value at the current
+// point in the program, and the VisitScope methods consult it to warn about
+// code that is certain to fail at runtime.
+
+type classShape struct {
+  superclass string
+  members map[string]bool
+  init *signature
+}
+
+type propertyRead struct {
+  class string
+  name token.Token
+}
+
+var facts []map[string]staticType
+var factBarrier int
+var shapes map[string]*classShape
+var propertyReads []propertyRead
+var looseProperties map[string]bool
+var analyzedClass string
+var inStaticMethod bool
+
+func beginAnalysis() {
+  facts = nil
+  factBarrier = 0
+  shapes = make(map[string]*classShape)
+  propertyReads = nil
+  looseProperties = make(map[string]bool)
+  analyzedClass = ""
+  inStaticMethod = false
+}
+
+func finishAnalysis() {
+  for _, read := range propertyReads {
+    if !looseProperties[read.name.Lexeme] && !hasMember(read.class, read.name.Lexeme) {
+      loxError.Warning(read.name, fmt.Sprintf("Property '%s' is never assigned on class '%s'.", read.name.Lexeme, className(read.class)))
+    }
+  }
+}
+
+// Shapes are keyed like the type checker's classes, by declaration, and
+// walks up the superclasses stop if a class repeats.
+func hasMember(class string, name string) bool {
+  seen := make(map[string]bool)
+  for class != "" && !seen[class] {
+    seen[class] = true
+    shape, ok := shapes[class]
+    if !ok {return true}
+    if shape.members[name] {return true}
+    class = shape.superclass
+  }
+  return false
+}
+
+func noteFact(name string, t staticType) {
+  if len(facts) == 0 {return}
+  facts[len(facts)-1][name] = t
+}
+
+// Variables declared outside the innermost function or loop may be changed
+// behind our back, so only constants, functions and classes are trusted there.
+func lookupFact(name string) staticType {
+  for i := len(scopes) - 1; i >= 0; i-- {
+    _, ok := scopes[i][name]
+    if !ok {continue}
+
+    t, ok := facts[i][name]
+    if !ok {return anyType}
+    if i < factBarrier && !constants[i][name] && t.kind != typekind.FUNCTION && t.kind != typekind.CLASS {
+      return anyType
+    }
+    return t
+  }
+  return anyType
+}
+
+func updateFact(name string, t staticType) {
+  for i := len(scopes) - 1; i >= 0; i-- {
+    _, ok := scopes[i][name]
+    if !ok {continue}
+
+    if i < factBarrier {t = anyType}
+    facts[i][name] = t
+    return
+  }
+}
+
+func snapshotFacts() []map[string]staticType {
+  return copyFacts(facts)
+}
+
+func copyFacts(source []map[string]staticType) []map[string]staticType {
+  snapshot := make([]map[string]staticType, len(source))
+  for i, scope := range source {
+    snapshot[i] = make(map[string]staticType)
+    for name, t := range scope {
+      snapshot[i][name] = t
+    }
+  }
+  return snapshot
+}
+
+func joinFacts(a []map[string]staticType, b []map[string]staticType) {
+  for i := range facts {
+    facts[i] = make(map[string]staticType)
+    for name, t := range a[i] {
+      other, ok := b[i][name]
+      if ok && other.String() == t.String() {
+        facts[i][name] = t
+      } else {
+        facts[i][name] = anyType
+      }
+    }
+  }
+}
+
+func shapeSignature(function Function) *signature {
+  sig := signature{nil, 0, function.Rest != nil, anyType}
+  for i, param := range function.Params {
+    sig.params = append(sig.params, anyType)
+    if !hasDefault(param) {sig.required = i + 1}
+  }
+  return &sig
+}
+
+func infer(expr Expr) staticType {
+  switch e := expr.(type) {
+  case Literal:
+    return e.VisitCheck()
+  case Grouping:
+    return infer(e.Expression)
+  case Variable:
+    return lookupFact(e.Name.Lexeme)
+  case Assign:
+    return infer(e.Value)
+  case This:
+    if analyzedClass != "" && !inStaticMethod {
+      return staticType{kind: typekind.INSTANCE, class: analyzedClass}
+    }
+  case List:
+    return staticType{kind: typekind.LIST}
+  case Tuple:
+    return staticType{kind: typekind.TUPLE}
+  case Unary:
+    if e.Operator.TokenType == token.MINUS {return staticType{kind: typekind.NUMBER}}
+    return staticType{kind: typekind.BOOL}
+  case Binary:
+    switch e.Operator.TokenType {
+    case token.MINUS, token.SLASH, token.STAR:
+      return staticType{kind: typekind.NUMBER}
+    case token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL, token.EQUAL_EQUAL, token.BANG_EQUAL, token.IS:
+      return staticType{kind: typekind.BOOL}
+    case token.PLUS:
+      left := infer(e.Left)
+      right := infer(e.Right)
+      if left.kind == typekind.NUMBER && right.kind == typekind.NUMBER {return left}
+      if left.kind == typekind.STRING || right.kind == typekind.STRING {
+        return staticType{kind: typekind.STRING}
+      }
+    }
+  case Call:
+    callee := infer(e.Callee)
+    if callee.kind == typekind.CLASS {
+      return staticType{kind: typekind.INSTANCE, class: callee.class}
+    }
+  }
+  return anyType
+}
+
+func analyzeBinary(e Binary) {
+  left := infer(e.Left)
+  right := infer(e.Right)
+  if left.kind == typekind.ANY || right.kind == typekind.ANY {return}
+
+  switch e.Operator.TokenType {
+  case token.PLUS:
+    if left.kind == typekind.NUMBER && right.kind == typekind.NUMBER {return}
+    if left.kind == typekind.STRING || right.kind == typekind.STRING {return}
+  case token.MINUS, token.SLASH, token.STAR, token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL:
+    if left.kind == typekind.NUMBER && right.kind == typekind.NUMBER {return}
+  case token.IS:
+    if right.kind == typekind.CLASS {return}
+  default:
+    return
+  }
+
+  loxError.Warning(e.Operator, fmt.Sprintf("'%s' on %s and %s will fail at runtime.", e.Operator.Lexeme, left, right))
+}
+
+func analyzeCall(e Call) {
+  callee := infer(e.Callee)
+
+  name := "This function"
+  v, ok := e.Callee.(Variable)
+  if ok {name = "'" + v.Name.Lexeme + "'"}
+
+  var sig *signature
+  switch callee.kind {
+  case typekind.ANY:
+    return
+  case typekind.FUNCTION:
+    sig = callee.signature
+  case typekind.CLASS:
+    seen := make(map[string]bool)
+    for class := callee.class; class != "" && !seen[class] && sig == nil; {
+      seen[class] = true
+      shape, ok := shapes[class]
+      if !ok {return}
+      sig = shape.init
+      class = shape.superclass
+    }
+    if sig == nil {sig = &signature{nil, 0, false, anyType}}
+  default:
+    loxError.Warning(e.Paren, fmt.Sprintf("Calling a %s value will fail at runtime; only functions and classes are callable.", callee))
+    return
+  }
+  if sig == nil || hasSpread(e.Arguments) {return}
+
+  count := len(e.Arguments) + len(e.Named)
+  max := len(sig.params)
+  if count >= sig.required && (sig.variadic || count <= max) {return}
+
+  var expected string
+  if sig.variadic {
+    expected = fmt.Sprintf("at least %d", sig.required)
+  } else if sig.required == max {
+    expected = fmt.Sprintf("%d", max)
+  } else {
+    expected = fmt.Sprintf("%d to %d", sig.required, max)
+  }
+  loxError.Warning(e.Paren, fmt.Sprintf("%s expects %s %s but is called with %d.", name, expected, plural(max, "argument"), count))
+}
+
+func analyzeGet(e Get) {
+  object := infer(e.Object)
+  if object.kind == typekind.INSTANCE {
+    propertyReads = append(propertyReads, propertyRead{object.class, e.Name})
+  }
+}
+
+func analyzeSet(e Set) {
+  object := infer(e.Object)
+  shape, ok := shapes[object.class]
+  if object.kind == typekind.INSTANCE && ok {
+    shape.members[e.Name.Lexeme] = true
+  } else {
+    looseProperties[e.Name.Lexeme] = true
+  }
+}
+
+func shapeClass(e Class) {
+  key := classKey(e.Name)
+  shape := &classShape{"", make(map[string]bool), nil}
+  // A superclass we know nothing about keeps its bare name, which has no
+  // shape, so its members are assumed to exist.
+  if e.Superclass != nil {
+    shape.superclass = e.Superclass.Name.Lexeme
+    superclass := infer(*e.Superclass)
+    if superclass.kind == typekind.CLASS {shape.superclass = superclass.class}
+  }
+
+  for _, method := range e.Methods {
+    shape.members[method.Name.Lexeme] = true
+    if method.Name.Lexeme == "init" {shape.init = shapeSignature(method)}
+  }
+  for _, getter := range e.Getters {
+    shape.members[getter.Name.Lexeme] = true
+  }
+  for _, method := range e.StaticMethods {
+    shape.members[method.Name.Lexeme] = true
+  }
+  for _, field := range e.Fields {
+    shape.members[field.Name.Lexeme] = true
+  }
+
+  shapes[key] = shape
+  if len(e.Decorators) > 0 {
+    noteFact(e.Name.Lexeme, anyType)
+  } else {
+    noteFact(e.Name.Lexeme, staticType{kind: typekind.CLASS, class: key})
+  }
+}
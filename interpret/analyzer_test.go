@@ -0,0 +1,42 @@
+package interpret_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzerWarnings(t *testing.T) {
+  tests := []struct {
+    name string
+    source string
+    warning string
+  }{
+    {"number plus bool", `fun anA() { var n = 1; var b = true; print n + b; }`, "'+' on Number and Bool will fail at runtime."},
+    {"string concatenation is fine", `fun anB() { var n = 1; print n + "s"; }`, ""},
+    {"comparing strings", `fun anC() { var a = "a"; print a < 1; }`, "'<' on String and Number will fail at runtime."},
+    {"is with a number", `fun anD() { var a = 1; print a is 2; }`, "'is' on Number and Number will fail at runtime."},
+    {"calling a string", `fun anE() { var s = "s"; s(); }`, "Calling a String value will fail at runtime; only functions and classes are callable."},
+    {"too few arguments", `fun anF(a, b) {} fun anG() { anF(1); }`, "'anF' expects 2 arguments but is called with 1."},
+    {"too many arguments with defaults", `fun anH(a, b = 1) {} fun anI() { anH(1, 2, 3); }`, "'anH' expects 1 to 2 arguments but is called with 3."},
+    {"variadic", `fun anJ(a, ...rest) {} fun anK() { anJ(); }`, "'anJ' expects at least 1 argument but is called with 0."},
+    {"spread is unchecked", `fun anL(a, b) {} fun anM(xs) { anL(...xs); }`, ""},
+    {"class initialiser arity", `class AnPoint { init(x, y) {} } fun anN() { AnPoint(1); }`, "'AnPoint' expects 2 arguments but is called with 1."},
+    {"inherited initialiser arity", `class AnBase { init(x) {} } class AnSub < AnBase {} fun anO() { AnSub(); }`, "'AnSub' expects 1 argument but is called with 0."},
+    {"property never assigned", `class AnBox { init() { this.value = 1; } } fun anP() { var box = AnBox(); print box.valeu; }`, "Property 'valeu' is never assigned on class 'AnBox'."},
+    {"inherited property", `class AnParent { init() { this.value = 1; } } class AnChild < AnParent {} fun anQ() { var c = AnChild(); print c.value; }`, ""},
+    {"this in a static method", `class AnStatic { class make() { return this.x; } }`, "'this' in a static method has no instance to refer to."},
+    {"branches join", `fun anR(c) { var v = 1; if (c) v = "s"; print v - 1; }`, ""},
+    {"both branches agree", `fun anS(c) { var v = 1; if (c) v = "a"; else v = "b"; print v - 1; }`, "'-' on String and Number will fail at runtime."},
+    {"outer variables are not trusted", `var anOuter = 1; fun anT() { anOuter(); }`, ""},
+  }
+  for _, test := range tests {
+    r := run(t, test.source)
+    if test.warning == "" {
+      if strings.Contains(r.errors, "Warning") {
+        t.Errorf("%s: unexpected warning:\n%s", test.name, r.errors)
+      }
+    } else if !strings.Contains(r.errors, test.warning) {
+      t.Errorf("%s: expected a warning containing %q, got:\n%s", test.name, test.warning, r.errors)
+    }
+  }
+}
@@ -58,6 +58,179 @@ func (e Assign) AstPrint() string {
   return parenthesize(fmt.Sprintf("= %s", e.Name.Lexeme), e.Value)
 }
 
+func (e List) AstPrint() string {
+  return parenthesize("list", e.Elements...)
+}
+
+func (e Tuple) AstPrint() string {
+  return parenthesize("tuple", e.Elements...)
+}
+
+func (e ClassExpr) AstPrint() string {
+  return e.Declaration.AstPrint()
+}
+
+func (e Old) AstPrint() string {
+  return parenthesize("old", e.Expression)
+}
+
+func (e Spread) AstPrint() string {
+  return parenthesize("...", e.Expression)
+}
+
+func (e Index) AstPrint() string {
+  return parenthesize("[]", e.Object, e.Index)
+}
+
+func (e SetIndex) AstPrint() string {
+  return parenthesize("[]=", e.Object, e.Index, e.Value)
+}
+
+func (e Destructure) AstPrint() string {
+  return parenthesize(fmt.Sprintf("= %s", e.Pattern.AstPrint()), e.Value)
+}
+
+func (e NamePattern) AstPrint() string {
+  return withDefault(e.Name.Lexeme, e.Default)
+}
+
+func (e ListPattern) AstPrint() string {
+  var parts []string
+  for _, element := range e.Elements {
+    parts = append(parts, element.AstPrint())
+  }
+  if e.Rest != nil {parts = append(parts, "..." + e.Rest.Lexeme)}
+  return withDefault("[" + strings.Join(parts, " ") + "]", e.Default)
+}
+
+func (e ObjectPattern) AstPrint() string {
+  var parts []string
+  for _, property := range e.Properties {
+    parts = append(parts, property.Key.Lexeme + ":" + property.Value.AstPrint())
+  }
+  if e.Rest != nil {parts = append(parts, "..." + e.Rest.Lexeme)}
+  return withDefault("{" + strings.Join(parts, " ") + "}", e.Default)
+}
+
+func (e Block) AstPrint() string {
+  return parenthesizeStmts("block", e.Statements...)
+}
+
+func (e Class) AstPrint() string {
+  name := "class " + e.Name.Lexeme
+  if e.Superclass != nil {name += " < " + e.Superclass.Name.Lexeme}
+
+  var members []Stmt
+  for _, method := range e.Methods {members = append(members, method)}
+  for _, getter := range e.Getters {members = append(members, getter)}
+  for _, method := range e.StaticMethods {members = append(members, method)}
+  for _, nested := range e.Classes {members = append(members, nested)}
+  return parenthesizeStmts(name, members...)
+}
+
+func (e Expression) AstPrint() string {
+  return parenthesize(";", e.Expression)
+}
+
+func (e Function) AstPrint() string {
+  var params []string
+  for _, param := range e.Params {
+    params = append(params, param.AstPrint())
+  }
+  if e.Rest != nil {params = append(params, "..." + e.Rest.Lexeme)}
+  return parenthesizeStmts(fmt.Sprintf("fun %s (%s)", e.Name.Lexeme, strings.Join(params, " ")), e.Body...)
+}
+
+func (e If) AstPrint() string {
+  if e.ElseBranch == nil {
+    return "(if " + e.Condition.AstPrint() + " " + e.ThenBranch.AstPrint() + ")"
+  }
+  return "(if " + e.Condition.AstPrint() + " " + e.ThenBranch.AstPrint() + " " + e.ElseBranch.AstPrint() + ")"
+}
+
+func (e Print) AstPrint() string {
+  return parenthesize("print", e.Expression)
+}
+
+func (e Return) AstPrint() string {
+  if e.Value == nil {return "(return)"}
+  return parenthesize("return", e.Value)
+}
+
+func (e Var) AstPrint() string {
+  keyword := "var"
+  if e.Constant {keyword = "const"}
+  if e.Initializer == nil {return "(" + keyword + " " + e.Pattern.AstPrint() + ")"}
+  return parenthesize(keyword + " " + e.Pattern.AstPrint(), e.Initializer)
+}
+
+func (e While) AstPrint() string {
+  if e.Else == nil {
+    return "(while " + e.Condition.AstPrint() + " " + e.Body.AstPrint() + ")"
+  }
+  return "(while " + e.Condition.AstPrint() + " " + e.Body.AstPrint() + " " + e.Else.AstPrint() + ")"
+}
+
+func (e DoWhile) AstPrint() string {
+  return "(do " + e.Body.AstPrint() + " " + e.Condition.AstPrint() + ")"
+}
+
+func (e Switch) AstPrint() string {
+  builder := strings.Builder{}
+  builder.WriteString("(switch " + e.Subject.AstPrint())
+  for _, c := range e.Cases {
+    name := "default"
+    if c.Values != nil {
+      name = "case"
+      for _, value := range c.Values {name += " " + value.AstPrint()}
+    }
+    if c.Fallthrough {name += " fallthrough"}
+    builder.WriteString(" " + parenthesizeStmts(name, c.Body...))
+  }
+  builder.WriteString(")")
+  return builder.String()
+}
+
+func (e Macro) AstPrint() string {
+  var parts []string
+  for _, tok := range e.Body {
+    parts = append(parts, tok.Lexeme)
+  }
+  return "(macro " + e.Name.Lexeme + " " + strings.Join(parts, " ") + ")"
+}
+
+func (e Assert) AstPrint() string {
+  if e.Message == nil {return parenthesize("assert", e.Condition)}
+  return parenthesize("assert", e.Condition, e.Message)
+}
+
+func (e Break) AstPrint() string {
+  return "(break)"
+}
+
+func (e Defer) AstPrint() string {
+  return parenthesize("defer", e.Call)
+}
+
+func withDefault(pattern string, def Expr) string {
+  if def == nil {return pattern}
+  return parenthesize("= " + pattern, def)
+}
+
+func parenthesizeStmts(name string, stmts... Stmt) string {
+  builder := strings.Builder{}
+  builder.WriteString("(")
+  builder.WriteString(name)
+
+  for _, stmt := range stmts {
+    builder.WriteString(" ")
+    builder.WriteString(stmt.AstPrint())
+  }
+  builder.WriteString(")")
+
+  return builder.String()
+}
+
 func parenthesize(name string, exprs... Expr) string {
   builder := strings.Builder{}
   builder.WriteString("(")
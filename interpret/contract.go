@@ -0,0 +1,176 @@
+package interpret
+
+import (
+	"fmt"
+	"lox/environment"
+	"lox/loxError"
+	"lox/token"
+)
+
+// CheckContracts turns off assertions and pre- and postconditions when false.
+var CheckContracts = true
+
+// The values of the old() expressions of every contract-checked call in
+// progress, innermost last.
+var oldValues []map[token.Token]any
+
+type contractScope struct {
+  function LoxFunction
+  env environment.Environment
+}
+
+// enterContracts checks the preconditions of a function and of every method
+// it overrides, then records the old() values their postconditions need.
+// An override may weaken what it requires, so the call may go ahead when the
+// clauses of any one of them hold. Inherited clauses see the values the
+// override's parameters were bound to, under the overridden method's own
+// parameter names.
+func (e LoxFunction) enterContracts(env environment.Environment, arguments []any) ([]contractScope, error) {
+  var scopes []contractScope
+  var bound []any
+  for function := &e; function != nil; function = function.Inherited {
+    declaration := function.Declaration
+    if len(declaration.Requires) == 0 && len(declaration.Ensures) == 0 {continue}
+
+    scope := contractScope{*function, env}
+    if function != &e {
+      if bound == nil {bound = e.boundArguments(env, arguments)}
+      var err error
+      scope.env, err = function.rebind(bound)
+      if err != nil {return nil, err}
+    }
+    scopes = append(scopes, scope)
+  }
+
+  err := checkPreconditions(scopes)
+  if err != nil {return nil, err}
+
+  snapshot := make(map[token.Token]any)
+  for _, scope := range scopes {
+    for _, old := range scope.function.Declaration.Olds {
+      value, err := evaluate(old.Expression, scope.env)
+      if err != nil {return nil, err}
+      snapshot[old.Keyword] = value
+    }
+  }
+
+  if len(scopes) > 0 {oldValues = append(oldValues, snapshot)}
+  return scopes, nil
+}
+
+// checkPreconditions reports the first failing clause of the innermost
+// function with preconditions, unless every clause of some other one holds.
+func checkPreconditions(scopes []contractScope) error {
+  var first error
+  for _, scope := range scopes {
+    requires := scope.function.Declaration.Requires
+    if len(requires) == 0 {continue}
+
+    what := "Precondition of '" + scope.function.Declaration.Name.Lexeme + "'"
+    var failure error
+    for _, clause := range requires {
+      var err error
+      failure, err = failedClause(scope.env, clause, what)
+      if err != nil {return err}
+      if failure != nil {break}
+    }
+    if failure == nil {return nil}
+    if first == nil {first = failure}
+  }
+  return first
+}
+
+// boundArguments returns the values e's parameters were bound to in env, with
+// any defaults already filled in, followed by whatever went into the rest
+// parameter.
+func (e LoxFunction) boundArguments(env environment.Environment, arguments []any) []any {
+  var bound []any
+  for i, param := range e.Declaration.Params {
+    var value any
+    if name, ok := param.(NamePattern); ok {
+      value, _ = environment.Get(&env, name.Name)
+    } else if i < len(arguments) {
+      value = arguments[i]
+    }
+    bound = append(bound, value)
+  }
+  if e.Declaration.Rest != nil {
+    rest, _ := environment.Get(&env, *e.Declaration.Rest)
+    if list, ok := rest.(*LoxList); ok {bound = append(bound, list.Elements...)}
+  } else if len(arguments) > len(bound) {
+    bound = append(bound, arguments[len(bound):]...)
+  }
+  return bound
+}
+
+// rebind binds values to e's parameters without evaluating their defaults,
+// which already ran when the override bound its own.
+func (e LoxFunction) rebind(values []any) (environment.Environment, error) {
+  declaration := e.Declaration
+  declaration.Params = make([]Pattern, len(e.Declaration.Params))
+  for i, param := range e.Declaration.Params {
+    declaration.Params[i] = withoutDefault(param)
+  }
+  e.Declaration = declaration
+  return e.bindParameters(values)
+}
+
+func withoutDefault(param Pattern) Pattern {
+  switch p := param.(type) {
+  case NamePattern:
+    p.Default = nil
+    return p
+  case ListPattern:
+    p.Default = nil
+    return p
+  case ObjectPattern:
+    p.Default = nil
+    return p
+  }
+  return param
+}
+
+func exitContracts(scopes []contractScope, value any, err error) (any, error) {
+  defer func() {oldValues = oldValues[:len(oldValues)-1]}()
+  if err != nil {return value, err}
+
+  for _, scope := range scopes {
+    environment.Define(&scope.env, "result", value)
+    for _, clause := range scope.function.Declaration.Ensures {
+      err := checkClause(scope.env, clause, "Postcondition of '" + scope.function.Declaration.Name.Lexeme + "'")
+      if err != nil {return nil, err}
+    }
+  }
+  return value, nil
+}
+
+func checkClause(env environment.Environment, clause Contract, what string) error {
+  failure, err := failedClause(env, clause, what)
+  if err != nil {return err}
+  return failure
+}
+
+// failedClause returns the error for a clause that doesn't hold, separately
+// from any error evaluating it.
+func failedClause(env environment.Environment, clause Contract, what string) (error, error) {
+  condition, err := evaluate(clause.Condition, env)
+  if err != nil {return nil, err}
+  if isTruthy(condition) {return nil, nil}
+
+  message := fmt.Sprintf("%s failed: %s", what, clause.Source)
+  if clause.Message != nil {
+    detail, err := evaluate(clause.Message, env)
+    if err != nil {return nil, err}
+    message += " (" + Stringify(detail) + ")"
+  }
+  return loxError.RuntimeError{clause.Keyword, message + "."}, nil
+}
+
+func (e Assert) VisitStmt(env environment.Environment) error {
+  if !CheckContracts {return nil}
+  return checkClause(env, Contract(e), "Assertion")
+}
+
+func (e Old) VisitExpr(env environment.Environment) (any, error) {
+  return oldValues[len(oldValues)-1][e.Keyword], nil
+}
@@ -0,0 +1,63 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestContracts(t *testing.T) {
+  runAll(t, []expectation{
+    {"assert holds", `assert 1 < 2; print "ok";`, "ok\n", ""},
+    {"assert fails", `assert 1 > 2;`, "", "Assertion failed: 1 > 2."},
+    {"assert message", `assert false, "because " + "reasons";`, "", "(because reasons)."},
+    {"precondition holds", `fun half(n) requires n >= 0 { return n / 2; } print half(4);`, "2\n", ""},
+    {"precondition fails", `fun root(n) requires n >= 0 { return n; } root(-1);`, "", "Precondition of 'root' failed: n >= 0."},
+    {"every precondition must hold", `fun both(n) requires n > 0 requires n < 10 { return n; } both(11);`, "", "failed: n < 10."},
+    {"precondition sees defaults", `fun withDefault(n = 3) requires n == 3 { return n; } print withDefault();`, "3\n", ""},
+    {"postcondition holds", `fun double(n) ensures result == n * 2 { return n * 2; } print double(3);`, "6\n", ""},
+    {"postcondition fails", `fun wrong(n) ensures result > n { return n; } wrong(1);`, "", "Postcondition of 'wrong' failed: result > n."},
+    {"old value", `var counter = 0; fun bump() ensures counter == old(counter) + 1 { counter = counter + 1; } bump(); print counter;`, "1\n", ""},
+    {"old value mismatch", `var total = 0; fun skip() ensures total == old(total) + 1 {} skip();`, "", "Postcondition of 'skip' failed"},
+    {"error in a clause", `fun broken(n) requires n.missing { return n; } broken(1);`, "", "Only instances have properties."},
+  })
+}
+
+func TestInheritedContracts(t *testing.T) {
+  runAll(t, []expectation{
+    {"inherited precondition applies", `
+class PositiveBase { take(n) requires n > 0 { return n; } }
+class PositiveSub < PositiveBase { take(m) { return m; } }
+PositiveSub().take(-1);`, "", "Precondition of 'take' failed: n > 0."},
+    {"override weakens its precondition", `
+class SmallBase { take(n) requires n < 10 { return n; } }
+class SmallSub < SmallBase { take(n) requires n < 100 { return n; } }
+print SmallSub().take(50);`, "50\n", ""},
+    {"inherited precondition alone is enough", `
+class EvenBase { take(n) requires n < 10 { return n; } }
+class EvenSub < EvenBase { take(n) requires n > 100 { return n; } }
+print EvenSub().take(4);`, "4\n", ""},
+    {"override's failure is reported when none hold", `
+class NoneBase { take(n) requires n < 0 { return n; } }
+class NoneSub < NoneBase { take(n) requires n > 100 { return n; } }
+NoneSub().take(5);`, "", "failed: n > 100."},
+    {"inherited clauses use the override's bound values", `
+class RenameBase { take(n) requires n == 7 { return n; } }
+class RenameSub < RenameBase { take(value = 7) { return value; } }
+print RenameSub().take();`, "7\n", ""},
+    {"defaults run once", `
+var defaultRuns = 0;
+fun countRun() { defaultRuns = defaultRuns + 1; return 1; }
+class OnceBase { take(n = countRun()) requires n > 0 { return n; } }
+class OnceSub < OnceBase { take(n = countRun()) requires n > 0 { return n; } }
+OnceSub().take();
+print defaultRuns;`, "1\n", ""},
+    {"inherited postcondition applies", `
+class ResultBase { make() ensures result != nil { return 1; } }
+class ResultSub < ResultBase { make() { return nil; } }
+ResultSub().make();`, "", "Postcondition of 'make' failed: result != nil."},
+    {"contracts through two levels", `
+class LevelA { take(n) requires n != 0 { return n; } }
+class LevelB < LevelA { take(n) { return n; } }
+class LevelC < LevelB { take(n) { return n; } }
+LevelC().take(0);`, "", "failed: n != 0."},
+  })
+}
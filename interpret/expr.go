@@ -26,6 +26,12 @@ type Call struct {
   Callee Expr
   Paren token.Token
   Arguments []Expr
+  Named []NamedArgument
+}
+
+type NamedArgument struct {
+  Name token.Token
+  Value Expr
 }
 
 type Get struct {
@@ -74,4 +80,46 @@ type Variable struct {
 type Assign struct {
   Name token.Token
   Value Expr
-}
\ No newline at end of file
+}
+
+type List struct {
+  Bracket token.Token
+  Elements []Expr
+}
+
+type ClassExpr struct {
+  Declaration Class
+}
+
+type Old struct {
+  Keyword token.Token
+  Expression Expr
+}
+
+type Spread struct {
+  Ellipsis token.Token
+  Expression Expr
+}
+
+type Index struct {
+  Object Expr
+  Bracket token.Token
+  Index Expr
+}
+
+type SetIndex struct {
+  Object Expr
+  Bracket token.Token
+  Index Expr
+  Value Expr
+}
+
+type Tuple struct {
+  Paren token.Token
+  Elements []Expr
+}
+
+type Destructure struct {
+  Pattern Pattern
+  Value Expr
+}
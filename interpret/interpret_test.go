@@ -0,0 +1,113 @@
+package interpret_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"lox/interpret"
+	"lox/loxError"
+	"lox/parse"
+	"lox/scan"
+)
+
+func TestMain(m *testing.M) {
+  interpret.DefineNatives()
+  os.Exit(m.Run())
+}
+
+// result is what running a program printed and how it failed, if it did.
+type result struct {
+  output string
+  errors string
+  staticError bool
+  runtimeError bool
+}
+
+// run takes a program through every stage, stopping where main would.
+func run(t *testing.T, source string) result {
+  t.Helper()
+  loxError.HadError = false
+  loxError.HadRuntimeError = false
+  interpret.ExitCode = -1
+
+  var r result
+  r.output, r.errors = capture(t, func() {
+    statements := parse.Parse(scan.ScanTokens(scan.NewScanner(source)))
+    if loxError.HadError {return}
+    interpret.InitialResolve(interpret.GlobalEnv, statements)
+    if loxError.HadError {return}
+    interpret.TypeCheck(statements)
+    if loxError.HadError {return}
+    interpret.Interpret(statements)
+  })
+  r.output = withoutWarnings(r.output)
+  r.staticError = loxError.HadError
+  r.runtimeError = loxError.HadRuntimeError
+  loxError.HadError = false
+  loxError.HadRuntimeError = false
+  return r
+}
+
+// The resolver prints unused variable warnings along with the output.
+func withoutWarnings(output string) string {
+  var lines []string
+  for _, line := range strings.SplitAfter(output, "\n") {
+    if !strings.HasPrefix(line, "Warning: ") {lines = append(lines, line)}
+  }
+  return strings.Join(lines, "")
+}
+
+func capture(t *testing.T, f func()) (string, string) {
+  t.Helper()
+  stdout, stderr := os.Stdout, os.Stderr
+  outRead, outWrite, err := os.Pipe()
+  if err != nil {t.Fatal(err)}
+  errRead, errWrite, err := os.Pipe()
+  if err != nil {t.Fatal(err)}
+
+  output, errors := make(chan string), make(chan string)
+  go func() {text, _ := io.ReadAll(outRead); output <- string(text)}()
+  go func() {text, _ := io.ReadAll(errRead); errors <- string(text)}()
+
+  os.Stdout, os.Stderr = outWrite, errWrite
+  defer func() {os.Stdout, os.Stderr = stdout, stderr}()
+  f()
+  outWrite.Close()
+  errWrite.Close()
+  return <-output, <-errors
+}
+
+// expectation is one program and what running it should do: print exactly
+// output, or fail with an error containing failure.
+type expectation struct {
+  name string
+  source string
+  output string
+  failure string
+}
+
+func (e expectation) check(t *testing.T, r result) {
+  t.Helper()
+  if e.failure == "" {
+    if r.staticError || r.runtimeError {
+      t.Errorf("%s: unexpected error:\n%s", e.name, r.errors)
+    } else if r.output != e.output {
+      t.Errorf("%s: printed %q, want %q", e.name, r.output, e.output)
+    }
+    return
+  }
+  if !r.staticError && !r.runtimeError {
+    t.Errorf("%s: expected an error containing %q, printed %q", e.name, e.failure, r.output)
+  } else if !strings.Contains(r.errors, e.failure) {
+    t.Errorf("%s: expected an error containing %q, got:\n%s", e.name, e.failure, r.errors)
+  }
+}
+
+func runAll(t *testing.T, tests []expectation) {
+  t.Helper()
+  for _, test := range tests {
+    test.check(t, run(t, test.source))
+  }
+}
@@ -5,15 +5,17 @@ import (
 	"fmt"
 	"lox/environment"
 	"lox/loxError"
-	"time"
+	"lox/token"
 )
 
 var GlobalEnv environment.Environment = environment.MakeEnvironment(nil, "asdf")
 var locals map[Expr]int = make(map[Expr]int)
+var deferring map[token.Token]bool = make(map[token.Token]bool)
+var tailCalls map[token.Token]bool = make(map[token.Token]bool)
 
 type ProtoLoxCallable struct {
   callMethod func(env environment.Environment, arguments []any) (any, error)
-  arityMethod func() int
+  arityMethod func() (int, int)
   stringMethod func() string
 }
 
@@ -21,7 +23,7 @@ func (p ProtoLoxCallable) Call(env environment.Environment, arguments []any) (an
   return p.callMethod(env, arguments)
 }
 
-func (p ProtoLoxCallable) Arity() int {
+func (p ProtoLoxCallable) Arity() (int, int) {
   return p.arityMethod()
 }
 
@@ -30,21 +32,13 @@ func (p ProtoLoxCallable) String() string {
 }
 
 func Interpret(statements []Stmt) {
-  environment.Define(&GlobalEnv, "clock", ProtoLoxCallable{
-    arityMethod: func() int {
-      return 0
-    },
-    
-    callMethod: func(env environment.Environment, arguments []any) (any, error) {
-      return time.Now().UnixMilli() / 1000, nil
-    },
-    stringMethod: func() string {
-      return "<native fn>"
-    },
-  })
-  
   for _, statement := range statements {
     err := execute(statement, GlobalEnv)
+    exit, ok := err.(ExitError)
+    if ok {
+      ExitCode = exit.Code
+      break
+    }
     if err != nil {
       rE, _ := err.(loxError.RuntimeError)
       loxError.ThrowRuntimeError(rE)
@@ -53,14 +47,92 @@ func Interpret(statements []Stmt) {
   }
 }
 
+func typeName(value any) string {
+  switch v := value.(type) {
+  case nil:
+    return "nil"
+  case float64:
+    return "number"
+  case string:
+    return "string"
+  case bool:
+    return "bool"
+  case *LoxList:
+    return "list"
+  case LoxTuple:
+    return "tuple"
+  case *LoxMap:
+    return "map"
+  case LoxClass:
+    return "class"
+  case LoxInstance:
+    if v.Class != nil {
+      key, _ := hashKey(*v.Class)
+      name, ok := nativeTypeNames[key]
+      if ok {return name}
+    }
+    return "instance"
+  case ProtoLoxCallable:
+    return "native"
+  case *fileHandle:
+    return "file"
+  case loxRegex:
+    return "regex"
+  case loxDate:
+    return "date"
+  case loxRandom:
+    return "random"
+  }
+  return "function"
+}
+
 func (e Expression) VisitStmt(env environment.Environment) error {
-  e.Expression.VisitExpr(env)
-  return nil
+  _, err := e.Expression.VisitExpr(env)
+  return err
 }
 
 func (e Function) VisitStmt(env environment.Environment) error {
-  function := LoxFunction{e, env, false}
-  return environment.Define(&env, e.Name.Lexeme, function)
+  function, err := decorate(env, e.Decorators, LoxFunction{e, env, false, false, nil})
+  if err != nil {return err}
+  return bindName(&env, e.Name, function, false)
+}
+
+// bindName defines a name the program declares, reporting an attempt to
+// redefine a constant at the name.
+func bindName(env *environment.Environment, name token.Token, value any, constant bool) error {
+  var err error
+  if constant {
+    err = environment.DefineConstant(env, name.Lexeme, value)
+  } else {
+    err = environment.Define(env, name.Lexeme, value)
+  }
+  if err != nil {return loxError.RuntimeError{name, err.Error()}}
+  return nil
+}
+
+func decorate(env environment.Environment, decorators []Decorator, value any) (any, error) {
+  values, err := evaluateDecorators(env, decorators)
+  if err != nil {return nil, err}
+  return applyDecorators(env, decorators, values, value)
+}
+
+func evaluateDecorators(env environment.Environment, decorators []Decorator) ([]any, error) {
+  values := make([]any, len(decorators))
+  for i := len(decorators) - 1; i >= 0; i-- {
+    decorator, err := evaluate(decorators[i].Expression, env)
+    if err != nil {return nil, err}
+    values[i] = decorator
+  }
+  return values, nil
+}
+
+func applyDecorators(env environment.Environment, decorators []Decorator, values []any, value any) (any, error) {
+  for i := len(decorators) - 1; i >= 0; i-- {
+    var err error
+    value, err = callValue(env, decorators[i].At, values[i], []any{value})
+    if err != nil {return nil, err}
+  }
+  return value, nil
 }
 
 func (e Print) VisitStmt(env environment.Environment) error {
@@ -77,7 +149,26 @@ type ReturnError struct {
 func (e ReturnError) Error() string {
   return "Return statement can only be used inside a function"
 }
+// A call in tail position hands its callee and arguments back to
+// LoxFunction.Call, which runs it in place of the returning function.
+type tailCall struct {
+  env environment.Environment
+  call Call
+  callee any
+  arguments []any
+}
+func (e tailCall) Error() string {
+  return "Return statement can only be used inside a function"
+}
+
 func (e Return) VisitStmt(env environment.Environment) error {
+  call, ok := e.Value.(Call)
+  if ok && tailCalls[call.Paren] {
+    callee, arguments, err := call.evaluateOperands(env)
+    if err != nil {return err}
+    return tailCall{env, call, callee, arguments}
+  }
+
   var value any = nil
   if e.Value != nil {
     var err error
@@ -96,7 +187,9 @@ func (e Var) VisitStmt(env environment.Environment) error {
     if err != nil {return err}
   }
 
-  return environment.Define(&env, e.Name.Lexeme, value)
+  return e.Pattern.VisitPattern(env, value, func(name token.Token, value any) error {
+    return bindName(&env, name, value, e.Constant)
+  })
 }
 
 func (e While) VisitStmt(env environment.Environment) error {
@@ -106,7 +199,7 @@ func (e While) VisitStmt(env environment.Environment) error {
     err = execute(e.Body, env)
     if err != nil {
       if err.Error() == "break" {
-        break
+        return nil
       }
       return err
     }
@@ -115,6 +208,97 @@ func (e While) VisitStmt(env environment.Environment) error {
     if err != nil {return err}
   }
 
+  if e.Else != nil {return execute(e.Else, env)}
+  return nil
+}
+
+func (e DoWhile) VisitStmt(env environment.Environment) error {
+  for {
+    err := execute(e.Body, env)
+    if err != nil {
+      if err.Error() == "break" {
+        return nil
+      }
+      return err
+    }
+
+    val, err := evaluate(e.Condition, env)
+    if err != nil {return err}
+    if !isTruthy(val) {return nil}
+  }
+}
+
+func (e Switch) VisitStmt(env environment.Environment) error {
+  subject, err := evaluate(e.Subject, env)
+  if err != nil {return err}
+
+  start := -1
+  for i, c := range e.Cases {
+    for _, value := range c.Values {
+      val, err := evaluate(value, env)
+      if err != nil {return err}
+      if isEqual(subject, val) {
+        start = i
+        break
+      }
+    }
+    if start >= 0 {break}
+  }
+  if start < 0 {
+    for i, c := range e.Cases {
+      if c.Values == nil {start = i}
+    }
+  }
+  if start < 0 {return nil}
+
+  for i := start; i < len(e.Cases); i++ {
+    err = executeBlock(e.Cases[i].Body, environment.MakeEnvironment(&env, ""))
+    if err != nil {
+      if err.Error() == "break" {
+        return nil
+      }
+      return err
+    }
+    if !e.Cases[i].Fallthrough {break}
+  }
+  return nil
+}
+
+type deferredCall struct {
+  env environment.Environment
+  call Call
+  callee any
+  arguments []any
+}
+
+var deferred [][]deferredCall
+
+// The callee and arguments are evaluated now; only the call waits.
+func (e Defer) VisitStmt(env environment.Environment) error {
+  callee, arguments, err := e.Call.evaluateOperands(env)
+  if err != nil {return err}
+  if len(deferred) == 0 {
+    return loxError.RuntimeError{e.Keyword, "Can't defer outside a function."}
+  }
+
+  frame := len(deferred) - 1
+  deferred[frame] = append(deferred[frame], deferredCall{env, e.Call, callee, arguments})
+  return nil
+}
+
+func runDeferred(env environment.Environment, value any, err error) (any, error) {
+  frame := deferred[len(deferred)-1]
+  deferred = deferred[:len(deferred)-1]
+
+  environment.Define(&env, "result", value)
+  for i := len(frame) - 1; i >= 0; i-- {
+    _, callErr := frame[i].call.apply(frame[i].env, frame[i].callee, frame[i].arguments)
+    if callErr != nil {err = callErr}
+  }
+  return environment.GetAt(&env, 0, "result"), err
+}
+
+func (e Macro) VisitStmt(env environment.Environment) error {
   return nil
 }
 
@@ -127,50 +311,75 @@ func (e Block) VisitStmt(env environment.Environment) error {
 }
 
 func (e Class) VisitStmt(env environment.Environment) error {
-  var superclass any
+  err := bindName(&env, e.Name, nil, false)
+  if err != nil {return err}
+  class, err := buildClass(e, env)
+  if err != nil {return err}
+  return environment.Assign(&env, e.Name, class)
+}
+
+func (e ClassExpr) VisitExpr(env environment.Environment) (any, error) {
+  return buildClass(e.Declaration, env)
+}
+
+func buildClass(e Class, env environment.Environment) (any, error) {
+  var superclass *LoxClass
   if e.Superclass != nil {
-    var err error
-    superclass, err = e.Superclass.VisitExpr(env)
-    if err != nil {return err}
+    value, err := e.Superclass.VisitExpr(env)
+    if err != nil {return nil, err}
 
-    var ok bool
-    superclass, ok = superclass.(LoxClass)
+    class, ok := value.(LoxClass)
     if !ok {
-      loxError.TokenError(e.Superclass.Name, "Superclass must be a class.")
+      return nil, loxError.RuntimeError{e.Superclass.Name, "Superclass must be a class."}
     }
+    superclass = &class
   }
-  
-  environment.Define(&env, e.Name.Lexeme, nil)
 
   envy := env
-  if e.Superclass != nil {
-    env = environment.MakeEnvironment(&env, "a")
-    environment.Define(&env, "super", superclass)
+  if superclass != nil {
+    env = environment.MakeEnvironment(&envy, "a")
+    environment.Define(&env, "super", *superclass)
   }
 
-  methods := make(map[string]LoxFunction)
+  methods := make(map[string]LoxCallable)
   for _, method := range e.Methods {
-    methods[method.Name.Lexeme] = LoxFunction{method, env, method.Name.Lexeme == "init"}
+    function := LoxFunction{method, env, method.Name.Lexeme == "init", true, nil}
+    if superclass != nil {
+      overridden, err := superclass.FindMethod(method.Name.Lexeme)
+      inherited, ok := overridden.(LoxFunction)
+      if err == nil && ok {function.Inherited = &inherited}
+    }
+
+    if len(method.Decorators) == 0 {
+      methods[method.Name.Lexeme] = function
+      continue
+    }
+
+    values, err := evaluateDecorators(env, method.Decorators)
+    if err != nil {return nil, err}
+    methods[method.Name.Lexeme] = &decoratedMethod{function, env, method.Decorators, values}
   }
 
   staticMethods := make(map[string]any)
   for _, method := range e.StaticMethods {
-    staticMethods[method.Name.Lexeme] = LoxFunction{method, env, false}
+    decorated, err := decorate(env, method.Decorators, LoxFunction{method, env, false, false, nil})
+    if err != nil {return nil, err}
+    staticMethods[method.Name.Lexeme] = decorated
   }
 
   getters := make(map[string]LoxFunction)
   for _, getter := range e.Getters {
-    getters[getter.Name.Lexeme] = LoxFunction{getter, env, false}
+    getters[getter.Name.Lexeme] = LoxFunction{getter, env, false, true, nil}
   }
 
-  super, _ := superclass.(LoxClass)
-  class := LoxClass{LoxInstance{nil, staticMethods}, e.Name, &super, methods, getters}
-
-  if superclass != nil {
-    env = envy
+  for _, nested := range e.Classes {
+    class, err := buildClass(nested, env)
+    if err != nil {return nil, err}
+    staticMethods[nested.Name.Lexeme] = class
   }
-  environment.Assign(&env, e.Name, class)
-  return nil
+
+  class := LoxClass{LoxInstance{nil, staticMethods, new(bool), nil, nil}, e.Name, superclass, methods, getters}
+  return decorate(envy, e.Decorators, class)
 }
 
 func (e If) VisitStmt(env environment.Environment) error {
@@ -179,7 +388,7 @@ func (e If) VisitStmt(env environment.Environment) error {
   if isTruthy(b) {
     return execute(e.ThenBranch, env)
   } else if e.ElseBranch != nil {
-    return execute(e.ThenBranch, env)
+    return execute(e.ElseBranch, env)
   }
 
   return nil
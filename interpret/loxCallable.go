@@ -4,5 +4,11 @@ import "lox/environment"
 
 type LoxCallable interface {
   Call(env environment.Environment, arguments []any) (any, error)
-  Arity() int
+  // Arity reports the fewest and most arguments accepted, with a maximum of
+  // -1 when the callable takes any number of trailing arguments.
+  Arity() (int, int)
+}
+
+type parameterized interface {
+  parameterNames() []string
 }
\ No newline at end of file
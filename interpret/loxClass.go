@@ -2,6 +2,7 @@ package interpret
 
 import (
 	"lox/environment"
+	"lox/loxError"
 	"lox/token"
 )
 
@@ -9,25 +10,36 @@ type LoxClass struct {
   LoxInstance
   Name token.Token
   Superclass *LoxClass
-  Methods map[string]LoxFunction
+  Methods map[string]LoxCallable
   Getters map[string] LoxFunction
 }
 
 func (e LoxClass) Call(env environment.Environment, arguments []any) (any, error) {
-  instance := LoxInstance{&e, make(map[string]any)}
+  instance := LoxInstance{&e, make(map[string]any), new(bool), newStorage(&e), make(map[*decoratedMethod]LoxCallable)}
   initializer, err := e.FindMethod("init")
   if err == nil {
-    initializer.Bind(instance).Call(env, arguments)
+    bound, err := bindMethod(initializer, instance)
+    if err != nil {return nil, err}
+    _, err = bound.Call(env, arguments)
+    if err != nil {return nil, err}
   }
   return instance, nil
 }
 
-func (e LoxClass) Arity() int {
+func (e LoxClass) Arity() (int, int) {
   initializer, err := e.FindMethod("init")
-  if err != nil {return 0}
+  if err != nil {return 0, 0}
   return initializer.Arity()
 }
 
+func (e LoxClass) parameterNames() []string {
+  initializer, err := e.FindMethod("init")
+  if err != nil {return nil}
+  p, ok := initializer.(parameterized)
+  if !ok {return nil}
+  return p.parameterNames()
+}
+
 type MethodNotFoundError struct {
   Name string
 }
@@ -35,7 +47,11 @@ func (e MethodNotFoundError) Error() string {
   return "Method" + e.Name + "not found"
 }
 
-func (e LoxClass) FindMethod(name string) (LoxFunction, error) {
+func (e LoxClass) String() string {
+  return e.Name.Lexeme
+}
+
+func (e LoxClass) FindMethod(name string) (LoxCallable, error) {
   val, ok := e.Methods[name]
   if ok {
     return val, nil
@@ -45,5 +61,67 @@ func (e LoxClass) FindMethod(name string) (LoxFunction, error) {
     return e.Superclass.FindMethod(name)
   }
 
-  return LoxFunction{}, MethodNotFoundError{name}
-}
\ No newline at end of file
+  return nil, MethodNotFoundError{name}
+}
+
+func isInstance(value any, class LoxClass) bool {
+  instance, ok := value.(LoxInstance)
+  if !ok {return false}
+
+  for ancestor := instance.Class; ancestor != nil; ancestor = ancestor.Superclass {
+    if isEqual(*ancestor, class) {return true}
+  }
+  return false
+}
+
+// Methods that aren't plain Lox functions know how to bind themselves.
+type bindable interface {
+  bind(instance LoxInstance) (LoxCallable, error)
+}
+
+// A decorated method is decorated for each instance it is bound to, so the
+// decorators are handed a method that already has 'this'. Each instance keeps
+// what its decorators returned.
+type decoratedMethod struct {
+  function LoxFunction
+  env environment.Environment
+  decorators []Decorator
+  values []any
+}
+
+func (e *decoratedMethod) bind(instance LoxInstance) (LoxCallable, error) {
+  bound, ok := instance.decorated[e]
+  if ok {return bound, nil}
+
+  value, err := applyDecorators(e.env, e.decorators, e.values, e.function.Bind(instance))
+  if err != nil {return nil, err}
+
+  bound, ok = value.(LoxCallable)
+  if !ok {
+    return nil, loxError.RuntimeError{e.function.Declaration.Name, "Decorated method must still be callable."}
+  }
+  if instance.decorated != nil {instance.decorated[e] = bound}
+  return bound, nil
+}
+
+func (e *decoratedMethod) Call(env environment.Environment, arguments []any) (any, error) {
+  return nil, loxError.RuntimeError{e.function.Declaration.Name, "Decorated method must be called on an instance."}
+}
+
+func (e *decoratedMethod) Arity() (int, int) {
+  return e.function.Arity()
+}
+
+func (e *decoratedMethod) String() string {
+  return e.function.String()
+}
+
+func bindMethod(method LoxCallable, instance LoxInstance) (LoxCallable, error) {
+  switch m := method.(type) {
+  case LoxFunction:
+    if m.IsMethod {return m.Bind(instance), nil}
+  case bindable:
+    return m.bind(instance)
+  }
+  return method, nil
+}
@@ -3,39 +3,128 @@ package interpret
 import (
 	"fmt"
 	"lox/environment"
+	"lox/token"
 )
 
 type LoxFunction struct {
   Declaration Function
   Closure environment.Environment
   IsInitializer bool
+  IsMethod bool
+  // Inherited is the superclass method this one overrides, whose contracts
+  // still apply.
+  Inherited *LoxFunction
 }
 
 func (e LoxFunction) Bind(instance LoxInstance) LoxFunction {
   env := environment.MakeEnvironment(&e.Closure, "")
   environment.Define(&env, "this", instance)
-  return LoxFunction{e.Declaration, env, e.IsInitializer}
+
+  inherited := e.Inherited
+  if inherited != nil {
+    bound := inherited.Bind(instance)
+    inherited = &bound
+  }
+  return LoxFunction{e.Declaration, env, e.IsInitializer, e.IsMethod, inherited}
 }
 
 func (e LoxFunction) Call(_ environment.Environment, arguments []any) (any, error) {
-  
+  for {
+    value, err := e.run(arguments)
+    tail, ok := err.(tailCall)
+    if !ok {return value, err}
+
+    function, ok := tail.callee.(LoxFunction)
+    if !ok {return tail.call.apply(tail.env, tail.callee, tail.arguments)}
+    if len(tail.call.Named) == 0 {
+      err = checkArity(tail.call.Paren, function, len(tail.arguments))
+      if err != nil {return nil, err}
+    }
+    e, arguments = function, tail.arguments
+  }
+}
+
+func (e LoxFunction) run(arguments []any) (any, error) {
+  envy, err := e.bindParameters(arguments)
+  if err != nil {return nil, err}
+
+  var contracts []contractScope
+  if CheckContracts {
+    contracts, err = e.enterContracts(envy, arguments)
+    if err != nil {return nil, err}
+  }
+  if deferring[e.Declaration.Name] {deferred = append(deferred, nil)}
+
+  var value any
+  err = executeBlock(e.Declaration.Body, envy)
+  rE, ok := err.(ReturnError)
+  if ok {value, err = rE.Value, nil}
+
+  if deferring[e.Declaration.Name] {
+    value, err = runDeferred(envy, value, err)
+  }
+  if len(contracts) > 0 {
+    // Postconditions must see what the tail call returns, so it can't
+    // replace this frame.
+    tail, ok := err.(tailCall)
+    if ok {value, err = tail.call.apply(tail.env, tail.callee, tail.arguments)}
+    value, err = exitContracts(contracts, value, err)
+  }
+  if e.IsInitializer && err == nil {return environment.GetAt(&e.Closure, 0, "this"), nil}
+  return value, err
+}
+
+func (e LoxFunction) bindParameters(arguments []any) (environment.Environment, error) {
   envy := environment.MakeEnvironment(&e.Closure, "func")
+  define := func(name token.Token, value any) error {
+    return bindName(&envy, name, value, false)
+  }
   for i := 0; i < len(e.Declaration.Params); i++ {
-    environment.Define(&envy, e.Declaration.Params[i].Lexeme, arguments[i])
+    var argument any
+    if i < len(arguments) {argument = arguments[i]}
+
+    err := e.Declaration.Params[i].VisitPattern(envy, argument, define)
+    if err != nil {return envy, err}
   }
-  err := executeBlock(e.Declaration.Body, envy)
-  rE, ok := err.(ReturnError)
-  if ok {
-    if e.IsInitializer {return environment.GetAt(&e.Closure, 0, "this"), nil}
-    return rE.Value, nil
+  if e.Declaration.Rest != nil {
+    rest := &LoxList{}
+    if len(arguments) > len(e.Declaration.Params) {
+      rest.Elements = append(rest.Elements, arguments[len(e.Declaration.Params):]...)
+    }
+    define(*e.Declaration.Rest, rest)
+  }
+  return envy, nil
+}
+
+func (e LoxFunction) Arity() (int, int) {
+  required := 0
+  for i, param := range e.Declaration.Params {
+    if !hasDefault(param) {required = i + 1}
   }
 
-  if e.IsInitializer {return environment.GetAt(&e.Closure, 0, "this"), nil}
-  return nil, err
+  if e.Declaration.Rest != nil {return required, -1}
+  return required, len(e.Declaration.Params)
+}
+
+func (e LoxFunction) parameterNames() []string {
+  var names []string
+  for _, param := range e.Declaration.Params {
+    name, _ := param.(NamePattern)
+    names = append(names, name.Name.Lexeme)
+  }
+  return names
 }
 
-func (e LoxFunction) Arity() int {
-  return len(e.Declaration.Params)
+func hasDefault(param Pattern) bool {
+  switch p := param.(type) {
+  case NamePattern:
+    return p.Default != nil
+  case ListPattern:
+    return p.Default != nil
+  case ObjectPattern:
+    return p.Default != nil
+  }
+  return false
 }
 
 func (e LoxFunction) String() string {
@@ -1,20 +1,40 @@
 package interpret
 
 import (
+	"fmt"
 	"lox/loxError"
 	"lox/token"
-	"reflect"
 )
 
 type LoxInstance struct {
   Class *LoxClass
   Fields map[string]any
+  frozen *bool
+  // storage holds a pointer to the Go state of instances of native classes.
+  storage any
+  decorated map[*decoratedMethod]LoxCallable
 }
 
 func (e LoxInstance) String() string {
+  if e.Class == nil {return "Instance"}
+  state, ok := e.storage.(fmt.Stringer)
+  if ok {return e.Class.Name.Lexeme + state.String()}
   return e.Class.Name.Lexeme + " Instance"
 }
 
+func (e LoxInstance) Has(name string) bool {
+  _, ok := e.Fields[name]
+  if ok {return true}
+
+  if e.Class != nil {
+    _, ok = e.Class.Getters[name]
+    if ok {return true}
+    _, err := e.Class.FindMethod(name)
+    return err == nil
+  }
+  return false
+}
+
 func (e LoxInstance) Get(name token.Token) (any, error) {
   if e.Class != nil {
     val, ok := e.Class.Getters[name.Lexeme]
@@ -29,15 +49,19 @@ func (e LoxInstance) Get(name token.Token) (any, error) {
   }
 
   if e.Class != nil {
-    method, _ := e.Class.FindMethod(name.Lexeme)
-    if !reflect.DeepEqual(method, LoxFunction{}) {
-      return method.Bind(e), nil
+    method, err := e.Class.FindMethod(name.Lexeme)
+    if err == nil {
+      return bindMethod(method, e)
     }
   }
 
   return nil, loxError.RuntimeError{name, "Undefined Property '" + name.Lexeme + "'."}
 }
 
-func (e LoxInstance) Set(name token.Token, value any) {
+func (e LoxInstance) Set(name token.Token, value any) error {
+  if e.frozen != nil && *e.frozen {
+    return loxError.RuntimeError{name, "Can't set property '" + name.Lexeme + "' on a frozen instance."}
+  }
   e.Fields[name.Lexeme] = value
+  return nil
 }
\ No newline at end of file
@@ -0,0 +1,35 @@
+package interpret
+
+import (
+	"lox/loxError"
+	"lox/token"
+	"strings"
+)
+
+type LoxList struct {
+  Elements []any
+}
+
+func (e *LoxList) String() string {
+  builder := strings.Builder{}
+  builder.WriteString("[")
+  for i, element := range e.Elements {
+    if i > 0 {builder.WriteString(", ")}
+    builder.WriteString(Stringify(element))
+  }
+  builder.WriteString("]")
+  return builder.String()
+}
+
+func elementIndex(bracket token.Token, elements []any, index any) (int, error) {
+  f, ok := index.(float64)
+  if !ok || f != float64(int(f)) {
+    return 0, loxError.RuntimeError{bracket, "Index must be an integer."}
+  }
+
+  i := int(f)
+  if i < 0 || i >= len(elements) {
+    return 0, loxError.RuntimeError{bracket, "Index out of range."}
+  }
+  return i, nil
+}
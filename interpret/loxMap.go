@@ -0,0 +1,135 @@
+package interpret
+
+import (
+	"fmt"
+	"lox/environment"
+	"strings"
+)
+
+// LoxMap keeps its keys in insertion order. Entries are looked up by
+// hashKey, so keys compare the same way == does.
+type LoxMap struct {
+  keys []any
+  values map[any]any
+}
+
+func NewLoxMap() *LoxMap {
+  return &LoxMap{nil, make(map[any]any)}
+}
+
+func (m *LoxMap) String() string {
+  builder := strings.Builder{}
+  builder.WriteString("{")
+  for i, key := range m.keys {
+    if i > 0 {builder.WriteString(", ")}
+    builder.WriteString(Stringify(key))
+    builder.WriteString(": ")
+    builder.WriteString(Stringify(m.values[mustHash(key)]))
+  }
+  builder.WriteString("}")
+  return builder.String()
+}
+
+func mapKey(key any) (any, error) {
+  hashed, ok := hashKey(key)
+  if !ok {return nil, fmt.Errorf("A %s can't be used as a map key.", typeName(key))}
+  return hashed, nil
+}
+
+// mustHash is only used on keys that are already in the map.
+func mustHash(key any) any {
+  hashed, _ := hashKey(key)
+  return hashed
+}
+
+func (m *LoxMap) Get(key any) (any, bool, error) {
+  hashed, err := mapKey(key)
+  if err != nil {return nil, false, err}
+  value, ok := m.values[hashed]
+  return value, ok, nil
+}
+
+func (m *LoxMap) Set(key any, value any) error {
+  hashed, err := mapKey(key)
+  if err != nil {return err}
+  _, ok := m.values[hashed]
+  if !ok {m.keys = append(m.keys, key)}
+  m.values[hashed] = value
+  return nil
+}
+
+func (m *LoxMap) Remove(key any) (any, error) {
+  hashed, err := mapKey(key)
+  if err != nil {return nil, err}
+  value, ok := m.values[hashed]
+  if !ok {return nil, nil}
+
+  delete(m.values, hashed)
+  for i, existing := range m.keys {
+    if mustHash(existing) == hashed {
+      m.keys = append(m.keys[:i], m.keys[i + 1:]...)
+      break
+    }
+  }
+  return value, nil
+}
+
+func init() {
+  registerNative("Map", native(0, 0, func(env environment.Environment, arguments []any) (any, error) {
+    return NewLoxMap(), nil
+  }))
+
+  registerMethods("map", methodTable{
+    Getters: map[string]func(receiver any) (any, error){
+      "length": func(receiver any) (any, error) {
+        return float64(len(receiver.(*LoxMap).keys)), nil
+      },
+    },
+    Methods: map[string]primitiveMethod{
+      "get": mapMethod(1, 2, func(m *LoxMap, arguments []any) (any, error) {
+        value, ok, err := m.Get(arguments[0])
+        if err != nil {return nil, err}
+        if !ok && len(arguments) > 1 {return arguments[1], nil}
+        return value, nil
+      }),
+      "set": mapMethod(2, 2, func(m *LoxMap, arguments []any) (any, error) {
+        return arguments[1], m.Set(arguments[0], arguments[1])
+      }),
+      "has": mapMethod(1, 1, func(m *LoxMap, arguments []any) (any, error) {
+        _, ok, err := m.Get(arguments[0])
+        return ok, err
+      }),
+      "remove": mapMethod(1, 1, func(m *LoxMap, arguments []any) (any, error) {
+        return m.Remove(arguments[0])
+      }),
+      "clear": mapMethod(0, 0, func(m *LoxMap, arguments []any) (any, error) {
+        m.keys = nil
+        m.values = make(map[any]any)
+        return nil, nil
+      }),
+      "keys": mapMethod(0, 0, func(m *LoxMap, arguments []any) (any, error) {
+        return &LoxList{append([]any(nil), m.keys...)}, nil
+      }),
+      "values": mapMethod(0, 0, func(m *LoxMap, arguments []any) (any, error) {
+        list := &LoxList{}
+        for _, key := range m.keys {
+          list.Elements = append(list.Elements, m.values[mustHash(key)])
+        }
+        return list, nil
+      }),
+      "entries": mapMethod(0, 0, func(m *LoxMap, arguments []any) (any, error) {
+        list := &LoxList{}
+        for _, key := range m.keys {
+          list.Elements = append(list.Elements, LoxTuple{[]any{key, m.values[mustHash(key)]}})
+        }
+        return list, nil
+      }),
+    },
+  })
+}
+
+func mapMethod(min int, max int, call func(m *LoxMap, arguments []any) (any, error)) primitiveMethod {
+  return primitiveMethod{min, max, func(env environment.Environment, receiver any, arguments []any) (any, error) {
+    return call(receiver.(*LoxMap), arguments)
+  }}
+}
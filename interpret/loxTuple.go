@@ -0,0 +1,29 @@
+package interpret
+
+import (
+	"strings"
+)
+
+type LoxTuple struct {
+  Elements []any
+}
+
+func (e LoxTuple) String() string {
+  builder := strings.Builder{}
+  builder.WriteString("(")
+  for i, element := range e.Elements {
+    if i > 0 {builder.WriteString(", ")}
+    builder.WriteString(Stringify(element))
+  }
+  if len(e.Elements) == 1 {builder.WriteString(",")}
+  builder.WriteString(")")
+  return builder.String()
+}
+
+func (e LoxTuple) equals(other LoxTuple) bool {
+  if len(e.Elements) != len(other.Elements) {return false}
+  for i := range e.Elements {
+    if !isEqual(e.Elements[i], other.Elements[i]) {return false}
+  }
+  return true
+}
@@ -0,0 +1,37 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestMacros(t *testing.T) {
+  runAll(t, []expectation{
+    {"expression macro", `macro square(x) { ((x) * (x)) } print square!(3);`, "9\n", ""},
+    {"statement macro", `macro show(e) { print e; } show!("hi");`, "hi\n", ""},
+    {"arguments with commas in brackets", `macro first(xs) { xs[0] } print first!([1, 2]);`, "1\n", ""},
+    {"nested invocation", `macro inc(x) { x + 1 } print inc!(inc!(1));`, "3\n", ""},
+    {"macro using a macro", `macro one() { 1 } macro two() { one!() + one!() } print two!();`, "2\n", ""},
+    {"introduced names don't capture", `
+macro swap(a, b) { var tmp = a; a = b; b = tmp; }
+var tmp = 1; var other = 2;
+swap!(tmp, other);
+print tmp; print other;`, "2\n1\n", ""},
+    {"introduced names don't leak", `
+macro define() { var hidden = 1; }
+var hidden = 0;
+{ define!(); print hidden; }`, "0\n", ""},
+    {"free names refer to the call site", `
+var macroLimit = 5;
+macro capped(x) { (x < macroLimit) }
+print capped!(3);`, "true\n", ""},
+    {"introduced functions are renamed", `
+macro twice(x) { fun helper(n) { return n * 2; } print helper(x); }
+fun helper(n) { return n; }
+twice!(4); print helper(4);`, "8\n4\n", ""},
+    {"undefined macro", `nothing!(1);`, "", "Undefined macro 'nothing'."},
+    {"wrong argument count", `macro pair(a, b) { a + b } print pair!(1);`, "", "Macro 'pair' expects 2 arguments but got 1."},
+    {"recursive macro", `macro forever() { forever!() } print forever!();`, "", "Too many macro expansions; is a macro expanding itself?"},
+    {"unterminated arguments", `macro id(x) { x } print id!(1;`, "", "Expect ')' after macro arguments."},
+    {"unterminated body", `macro broken() { print 1;`, "", "Unterminated macro body."},
+  })
+}
@@ -0,0 +1,542 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"lox/token"
+	"sort"
+	"strings"
+)
+
+// Native classes are ordinary classes whose methods are Go functions. Their
+// state lives in the instance's storage, so a Lox class can extend one and
+// call its methods through super like any other superclass.
+type nativeMethod[T any] struct {
+  min int
+  max int
+  call func(env environment.Environment, state *T, arguments []any) (any, error)
+}
+
+// The state of an instance is made when it is constructed, by the nearest
+// native class it descends from.
+var nativeConstructors = make(map[any]func() any)
+
+// Instances made by a native class itself report its name, in lower case, as
+// their type. Instances of subclasses are plain instances.
+var nativeTypeNames = make(map[any]string)
+
+func newStorage(class *LoxClass) any {
+  for ancestor := class; ancestor != nil; ancestor = ancestor.Superclass {
+    key, _ := hashKey(*ancestor)
+    create, ok := nativeConstructors[key]
+    if ok {return create()}
+  }
+  return nil
+}
+
+func nativeClass[T any](name string, create func() *T, methods map[string]nativeMethod[T]) *LoxClass {
+  class := &LoxClass{LoxInstance{nil, make(map[string]any), new(bool), nil, nil}, token.Token{token.IDENTIFIER, name, nil, 0, 0}, nil, make(map[string]LoxCallable), make(map[string]LoxFunction)}
+  for methodName, method := range methods {
+    methodName, method := methodName, method
+    unbound := native(method.min, method.max, func(env environment.Environment, arguments []any) (any, error) {
+      return nil, fmt.Errorf("'%s' must be called on a %s.", methodName, name)
+    })
+    class.Methods[methodName] = nativeBinding{unbound, func(instance LoxInstance) (LoxCallable, error) {
+      return native(method.min, method.max, func(env environment.Environment, arguments []any) (any, error) {
+        state, ok := instance.storage.(*T)
+        if !ok {return nil, fmt.Errorf("Expected a %s instance.", name)}
+        return method.call(env, state, arguments)
+      }), nil
+    }}
+  }
+
+  key, _ := hashKey(*class)
+  nativeConstructors[key] = func() any {return create()}
+  nativeTypeNames[key] = strings.ToLower(name)
+  registerNative(name, *class)
+  return class
+}
+
+// Methods of native classes are natives bound to the state of their instance.
+type nativeBinding struct {
+  ProtoLoxCallable
+  bindTo func(instance LoxInstance) (LoxCallable, error)
+}
+
+func (e nativeBinding) bind(instance LoxInstance) (LoxCallable, error) {
+  return e.bindTo(instance)
+}
+
+func nativeInstance(class *LoxClass, state any) LoxInstance {
+  return LoxInstance{class, make(map[string]any), new(bool), state, nil}
+}
+
+// Native collections can be passed wherever a list is expected.
+type collection interface {
+  elements() []any
+}
+
+func collectionElements(value any) ([]any, bool) {
+  switch sequence := value.(type) {
+  case *LoxList:
+    return sequence.Elements, true
+  case LoxTuple:
+    return sequence.Elements, true
+  case LoxInstance:
+    c, ok := sequence.storage.(collection)
+    if ok {return c.elements(), true}
+  }
+  return nil, false
+}
+
+func joinElements(elements []any) string {
+  var parts []string
+  for _, element := range elements {
+    parts = append(parts, Stringify(element))
+  }
+  return strings.Join(parts, ", ")
+}
+
+// compareValues orders numbers and strings, the only values with a natural
+// order.
+func compareValues(a any, b any) (int, error) {
+  switch x := a.(type) {
+  case float64:
+    y, ok := b.(float64)
+    if ok {
+      if x < y {return -1, nil}
+      if x > y {return 1, nil}
+      return 0, nil
+    }
+  case string:
+    y, ok := b.(string)
+    if ok {return strings.Compare(x, y), nil}
+  }
+  return 0, fmt.Errorf("Can't compare %s with %s.", typeName(a), typeName(b))
+}
+
+type setState struct {
+  members *LoxMap
+}
+
+func (s *setState) elements() []any {
+  return append([]any(nil), s.members.keys...)
+}
+
+func (s *setState) String() string {
+  return "{" + joinElements(s.members.keys) + "}"
+}
+
+func newSet(elements []any) (*setState, error) {
+  s := &setState{NewLoxMap()}
+  for _, element := range elements {
+    err := s.members.Set(element, true)
+    if err != nil {return nil, err}
+  }
+  return s, nil
+}
+
+var setClass *LoxClass
+
+// setOperation builds a new Set from the members of this set that keep
+// accepts, given whether other also has them, optionally followed by all of
+// other's members.
+func setOperation(name string, keep func(inOther bool) bool, addOthers bool) nativeMethod[setState] {
+  return nativeMethod[setState]{1, 1, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+    others, ok := collectionElements(arguments[0])
+    if !ok {return nil, fmt.Errorf("Argument 1 of '%s' must be a collection, not %s.", name, typeName(arguments[0]))}
+    other, err := newSet(others)
+    if err != nil {return nil, err}
+
+    var members []any
+    for _, member := range s.members.keys {
+      _, inOther, _ := other.members.Get(member)
+      if keep(inOther) {members = append(members, member)}
+    }
+    if addOthers {members = append(members, others...)}
+
+    result, err := newSet(members)
+    if err != nil {return nil, err}
+    return nativeInstance(setClass, result), nil
+  }}
+}
+
+type dequeState struct {
+  items []any
+  head int
+  count int
+}
+
+func (d *dequeState) at(i int) any {
+  return d.items[(d.head + i) % len(d.items)]
+}
+
+func (d *dequeState) elements() []any {
+  elements := make([]any, d.count)
+  for i := range elements {
+    elements[i] = d.at(i)
+  }
+  return elements
+}
+
+func (d *dequeState) String() string {
+  return "[" + joinElements(d.elements()) + "]"
+}
+
+func (d *dequeState) grow() {
+  if d.count < len(d.items) {return}
+  items := make([]any, 2 * len(d.items) + 4)
+  copy(items, d.elements())
+  d.items = items
+  d.head = 0
+}
+
+func (d *dequeState) end(name string, front bool) (int, error) {
+  if d.count == 0 {return 0, fmt.Errorf("Can't %s an empty deque.", name)}
+  if front {return d.head, nil}
+  return (d.head + d.count - 1) % len(d.items), nil
+}
+
+type sortedEntry struct {
+  key any
+  value any
+}
+
+type sortedMapState struct {
+  entries []sortedEntry
+}
+
+func (m *sortedMapState) String() string {
+  var parts []string
+  for _, entry := range m.entries {
+    parts = append(parts, Stringify(entry.key) + ": " + Stringify(entry.value))
+  }
+  return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// Keys must all be numbers or all be strings, which keeps every comparison
+// between them valid.
+func (m *sortedMapState) checkKey(key any) error {
+  _, number := key.(float64)
+  _, text := key.(string)
+  if !number && !text {return fmt.Errorf("SortedMap keys must be numbers or strings, not %s.", typeName(key))}
+  if len(m.entries) > 0 {
+    _, err := compareValues(m.entries[0].key, key)
+    if err != nil {return errors.New("SortedMap keys must all be numbers or all be strings.")}
+  }
+  return nil
+}
+
+// search returns the index of the first entry whose key is not less than key.
+func (m *sortedMapState) search(key any) int {
+  return sort.Search(len(m.entries), func(i int) bool {
+    order, _ := compareValues(m.entries[i].key, key)
+    return order >= 0
+  })
+}
+
+func (m *sortedMapState) find(key any) (int, bool) {
+  i := m.search(key)
+  if i == len(m.entries) {return i, false}
+  order, err := compareValues(m.entries[i].key, key)
+  return i, err == nil && order == 0
+}
+
+func (m *sortedMapState) entryAt(i int) any {
+  if i < 0 || i >= len(m.entries) {return nil}
+  return LoxTuple{[]any{m.entries[i].key, m.entries[i].value}}
+}
+
+func (m *sortedMapState) list(from int, to int, item func(entry sortedEntry) any) *LoxList {
+  list := &LoxList{}
+  for _, entry := range m.entries[from:to] {
+    list.Elements = append(list.Elements, item(entry))
+  }
+  return list
+}
+
+func sortedMapList(item func(entry sortedEntry) any) nativeMethod[sortedMapState] {
+  return nativeMethod[sortedMapState]{0, 0, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+    return m.list(0, len(m.entries), item), nil
+  }}
+}
+
+// A priority queue is a binary min-heap under its comparator, which returns
+// a negative number when its first argument should come out first.
+type priorityQueueState struct {
+  items []any
+  comparator any
+}
+
+func (q *priorityQueueState) String() string {
+  return fmt.Sprintf("(%d items)", len(q.items))
+}
+
+func (q *priorityQueueState) less(env environment.Environment, a any, b any) (bool, error) {
+  order, err := comparator(env, q.comparator)(a, b)
+  return order < 0, err
+}
+
+// push and pop find where items go before moving any, so a comparator that
+// fails leaves the queue as it was.
+func (q *priorityQueueState) push(env environment.Environment, item any) error {
+  i := len(q.items)
+  for i > 0 {
+    parent := (i - 1) / 2
+    less, err := q.less(env, item, q.items[parent])
+    if err != nil {return err}
+    if !less {break}
+    i = parent
+  }
+
+  q.items = append(q.items, nil)
+  for j := len(q.items) - 1; j > i; j = (j - 1) / 2 {
+    q.items[j] = q.items[(j - 1) / 2]
+  }
+  q.items[i] = item
+  return nil
+}
+
+func (q *priorityQueueState) pop(env environment.Environment) (any, error) {
+  if len(q.items) == 0 {return nil, errors.New("Can't pop from an empty priority queue.")}
+  top := q.items[0]
+  last := len(q.items) - 1
+  item := q.items[last]
+
+  path := []int{0}
+  for i := 0; ; {
+    smallest := -1
+    for _, child := range []int{2 * i + 1, 2 * i + 2} {
+      if child >= last {continue}
+      if smallest < 0 {
+        smallest = child
+        continue
+      }
+      less, err := q.less(env, q.items[child], q.items[smallest])
+      if err != nil {return nil, err}
+      if less {smallest = child}
+    }
+    if smallest < 0 {break}
+    less, err := q.less(env, q.items[smallest], item)
+    if err != nil {return nil, err}
+    if !less {break}
+    path = append(path, smallest)
+    i = smallest
+  }
+
+  for k := 1; k < len(path); k++ {
+    q.items[path[k - 1]] = q.items[path[k]]
+  }
+  q.items[path[len(path) - 1]] = item
+  q.items = q.items[:last]
+  return top, nil
+}
+
+func init() {
+  setClass = nativeClass("Set", func() *setState {return &setState{NewLoxMap()}}, map[string]nativeMethod[setState]{
+    "init": {0, 1, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      if len(arguments) == 0 {return nil, nil}
+      elements, ok := collectionElements(arguments[0])
+      if !ok {return nil, fmt.Errorf("Argument 1 of 'Set' must be a collection, not %s.", typeName(arguments[0]))}
+      for _, element := range elements {
+        err := s.members.Set(element, true)
+        if err != nil {return nil, err}
+      }
+      return nil, nil
+    }},
+    "add": {1, 1, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      return nil, s.members.Set(arguments[0], true)
+    }},
+    "remove": {1, 1, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      removed, err := s.members.Remove(arguments[0])
+      return removed != nil, err
+    }},
+    "has": {1, 1, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      _, ok, err := s.members.Get(arguments[0])
+      return ok, err
+    }},
+    "size": {0, 0, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      return float64(len(s.members.keys)), nil
+    }},
+    "clear": {0, 0, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      s.members = NewLoxMap()
+      return nil, nil
+    }},
+    "toList": {0, 0, func(env environment.Environment, s *setState, arguments []any) (any, error) {
+      return &LoxList{s.elements()}, nil
+    }},
+    "union": setOperation("union", func(inOther bool) bool {return true}, true),
+    "intersection": setOperation("intersection", func(inOther bool) bool {return inOther}, false),
+    "difference": setOperation("difference", func(inOther bool) bool {return !inOther}, false),
+  })
+
+  nativeClass("Deque", func() *dequeState {return &dequeState{}}, map[string]nativeMethod[dequeState]{
+    "pushBack": {1, 1, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      d.grow()
+      d.items[(d.head + d.count) % len(d.items)] = arguments[0]
+      d.count++
+      return nil, nil
+    }},
+    "pushFront": {1, 1, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      d.grow()
+      d.head = (d.head - 1 + len(d.items)) % len(d.items)
+      d.items[d.head] = arguments[0]
+      d.count++
+      return nil, nil
+    }},
+    "popBack": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      i, err := d.end("pop from", false)
+      if err != nil {return nil, err}
+      item := d.items[i]
+      d.items[i] = nil
+      d.count--
+      return item, nil
+    }},
+    "popFront": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      i, err := d.end("pop from", true)
+      if err != nil {return nil, err}
+      item := d.items[i]
+      d.items[i] = nil
+      d.head = (d.head + 1) % len(d.items)
+      d.count--
+      return item, nil
+    }},
+    "peekBack": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      i, err := d.end("peek at", false)
+      if err != nil {return nil, err}
+      return d.items[i], nil
+    }},
+    "peekFront": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      i, err := d.end("peek at", true)
+      if err != nil {return nil, err}
+      return d.items[i], nil
+    }},
+    "size": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      return float64(d.count), nil
+    }},
+    "clear": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      *d = dequeState{}
+      return nil, nil
+    }},
+    "toList": {0, 0, func(env environment.Environment, d *dequeState, arguments []any) (any, error) {
+      return &LoxList{d.elements()}, nil
+    }},
+  })
+
+  nativeClass("SortedMap", func() *sortedMapState {return &sortedMapState{}}, map[string]nativeMethod[sortedMapState]{
+    "set": {2, 2, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      err := m.checkKey(arguments[0])
+      if err != nil {return nil, err}
+      i, found := m.find(arguments[0])
+      if found {
+        m.entries[i].value = arguments[1]
+      } else {
+        m.entries = append(m.entries[:i], append([]sortedEntry{{arguments[0], arguments[1]}}, m.entries[i:]...)...)
+      }
+      return arguments[1], nil
+    }},
+    "get": {1, 2, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      i, found := m.find(arguments[0])
+      if found {return m.entries[i].value, nil}
+      if len(arguments) > 1 {return arguments[1], nil}
+      return nil, nil
+    }},
+    "has": {1, 1, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      _, found := m.find(arguments[0])
+      return found, nil
+    }},
+    "remove": {1, 1, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      i, found := m.find(arguments[0])
+      if !found {return nil, nil}
+      value := m.entries[i].value
+      m.entries = append(m.entries[:i], m.entries[i + 1:]...)
+      return value, nil
+    }},
+    "size": {0, 0, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      return float64(len(m.entries)), nil
+    }},
+    "clear": {0, 0, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      m.entries = nil
+      return nil, nil
+    }},
+    "keys": sortedMapList(func(entry sortedEntry) any {return entry.key}),
+    "values": sortedMapList(func(entry sortedEntry) any {return entry.value}),
+    "entries": sortedMapList(func(entry sortedEntry) any {return LoxTuple{[]any{entry.key, entry.value}}}),
+    "first": {0, 0, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      return m.entryAt(0), nil
+    }},
+    "last": {0, 0, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      return m.entryAt(len(m.entries) - 1), nil
+    }},
+    // floor is the last entry with a key no greater than the one given, and
+    // ceiling the first with a key no less than it.
+    "floor": {1, 1, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      err := m.checkKey(arguments[0])
+      if err != nil {return nil, err}
+      i, found := m.find(arguments[0])
+      if found {return m.entryAt(i), nil}
+      return m.entryAt(i - 1), nil
+    }},
+    "ceiling": {1, 1, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      err := m.checkKey(arguments[0])
+      if err != nil {return nil, err}
+      return m.entryAt(m.search(arguments[0])), nil
+    }},
+    // range lists the entries with keys from low up to but not including
+    // high. A nil bound leaves that end open.
+    "range": {2, 2, func(env environment.Environment, m *sortedMapState, arguments []any) (any, error) {
+      from, to := 0, len(m.entries)
+      if arguments[0] != nil {
+        err := m.checkKey(arguments[0])
+        if err != nil {return nil, err}
+        from = m.search(arguments[0])
+      }
+      if arguments[1] != nil {
+        err := m.checkKey(arguments[1])
+        if err != nil {return nil, err}
+        to = m.search(arguments[1])
+      }
+      if to < from {to = from}
+      return m.list(from, to, func(entry sortedEntry) any {return LoxTuple{[]any{entry.key, entry.value}}}), nil
+    }},
+  })
+
+  nativeClass("PriorityQueue", func() *priorityQueueState {return &priorityQueueState{}}, map[string]nativeMethod[priorityQueueState]{
+    "init": {0, 1, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      if len(arguments) == 0 || arguments[0] == nil {return nil, nil}
+      _, ok := arguments[0].(LoxCallable)
+      if !ok {return nil, fmt.Errorf("Comparator must be a function, not %s.", typeName(arguments[0]))}
+      q.comparator = arguments[0]
+      return nil, nil
+    }},
+    "push": {1, 1, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      return nil, q.push(env, arguments[0])
+    }},
+    "pop": {0, 0, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      return q.pop(env)
+    }},
+    "peek": {0, 0, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      if len(q.items) == 0 {return nil, errors.New("Can't peek at an empty priority queue.")}
+      return q.items[0], nil
+    }},
+    "size": {0, 0, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      return float64(len(q.items)), nil
+    }},
+    "clear": {0, 0, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      q.items = nil
+      return nil, nil
+    }},
+    // toList drains a copy of the queue, so the list is in priority order.
+    "toList": {0, 0, func(env environment.Environment, q *priorityQueueState, arguments []any) (any, error) {
+      drained := &priorityQueueState{append([]any(nil), q.items...), q.comparator}
+      list := &LoxList{}
+      for len(drained.items) > 0 {
+        item, err := drained.pop(env)
+        if err != nil {return nil, err}
+        list.Elements = append(list.Elements, item)
+      }
+      return list, nil
+    }},
+  })
+}
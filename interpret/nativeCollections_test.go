@@ -0,0 +1,75 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestCollectionTypeNames(t *testing.T) {
+  runAll(t, []expectation{
+    {"set", "print type(Set());", "set\n", ""},
+    {"deque", "print type(Deque());", "deque\n", ""},
+    {"sorted map", "print type(SortedMap());", "sortedmap\n", ""},
+    {"priority queue", "print type(PriorityQueue());", "priorityqueue\n", ""},
+    {"subclass", "class Bag < Set {} print type(Bag());", "instance\n", ""},
+  })
+}
+
+func TestSet(t *testing.T) {
+  runAll(t, []expectation{
+    {"from a list", `var scA = Set([1, 2, 2]); print scA; print scA.size();`, "Set{1, 2}\n2\n", ""},
+    {"add, has and remove", `var scB = Set(); scB.add("x"); print scB.has("x"); scB.remove("x"); print scB.has("x");`, "true\nfalse\n", ""},
+    {"clear", `var scC = Set([1]); scC.clear(); print scC.toList();`, "[]\n", ""},
+    {"not a collection", `Set(1);`, "", "Argument 1 of 'Set' must be a collection, not number."},
+    {"unhashable member", `Set().add([1]);`, "", "A list can't be used as a map key."},
+  })
+}
+
+func TestDeque(t *testing.T) {
+  runAll(t, []expectation{
+    {"both ends", `var dqA = Deque(); dqA.pushBack(1); dqA.pushFront(0); dqA.pushBack(2); print dqA; print dqA.popFront(); print dqA.popBack(); print dqA.toList();`, "Deque[0, 1, 2]\n0\n2\n[1]\n", ""},
+    {"peek", `var dqB = Deque(); dqB.pushBack(1); dqB.pushBack(2); print dqB.peekFront(); print dqB.peekBack(); print dqB.size();`, "1\n2\n2\n", ""},
+    {"grows past its capacity", `var dqC = Deque(); for (var i = 0; i < 40; i = i + 1) dqC.pushFront(i); print dqC.size(); print dqC.peekBack();`, "40\n0\n", ""},
+    {"pop from empty", `Deque().popFront();`, "", "Can't pop from an empty deque."},
+    {"peek at empty", `Deque().peekBack();`, "", "Can't peek at an empty deque."},
+  })
+}
+
+func TestSortedMap(t *testing.T) {
+  runAll(t, []expectation{
+    {"keeps keys sorted", `var smA = SortedMap(); smA.set(3, "c"); smA.set(1, "a"); smA.set(2, "b"); print smA; print smA.keys();`, "SortedMap{1: a, 2: b, 3: c}\n[1, 2, 3]\n", ""},
+    {"get with a default", `var smB = SortedMap(); smB.set("k", 1); print smB.get("k"); print smB.get("z", 0); print smB.get("z");`, "1\n0\nnil\n", ""},
+    {"remove", `var smC = SortedMap(); smC.set(1, "a"); print smC.remove(1); print smC.remove(1); print smC.size();`, "a\nnil\n0\n", ""},
+    {"first and last", `var smD = SortedMap(); print smD.first(); smD.set(2, "b"); smD.set(1, "a"); print smD.first(); print smD.last();`, "nil\n(1, a)\n(2, b)\n", ""},
+    {"floor and ceiling", `var smE = SortedMap(); smE.set(1, "a"); smE.set(3, "c"); print smE.floor(2); print smE.floor(3); print smE.floor(0); print smE.ceiling(2); print smE.ceiling(4);`, "(1, a)\n(3, c)\nnil\n(3, c)\nnil\n", ""},
+    {"range excludes the upper bound", `var smF = SortedMap(); for (var i = 1; i <= 4; i = i + 1) smF.set(i, i * 10); print smF.range(2, 4);`, "[(2, 20), (3, 30)]\n", ""},
+    {"range between keys", `var smG = SortedMap(); smG.set(1, "a"); smG.set(3, "c"); smG.set(5, "e"); print smG.range(2, 4);`, "[(3, c)]\n", ""},
+    {"range with open ends", `var smH = SortedMap(); smH.set(1, "a"); smH.set(2, "b"); print smH.range(nil, 2); print smH.range(2, nil); print smH.range(nil, nil);`, "[(1, a)]\n[(2, b)]\n[(1, a), (2, b)]\n", ""},
+    {"empty and inverted ranges", `var smI = SortedMap(); smI.set(1, "a"); smI.set(2, "b"); print smI.range(2, 2); print smI.range(2, 1); print smI.range(5, 9);`, "[]\n[]\n[]\n", ""},
+    {"range on an empty map", `print SortedMap().range(1, 2);`, "[]\n", ""},
+    {"range bound of the wrong kind", `var smJ = SortedMap(); smJ.set(1, "a"); smJ.range("a", nil);`, "", "SortedMap keys must all be numbers or all be strings."},
+    {"range bound that isn't a key", `SortedMap().range(nil, true);`, "", "SortedMap keys must be numbers or strings, not bool."},
+    {"mixed keys", `var smK = SortedMap(); smK.set(1, "a"); smK.set("b", 2);`, "", "SortedMap keys must all be numbers or all be strings."},
+    {"bad key", `SortedMap().set(nil, 1);`, "", "SortedMap keys must be numbers or strings, not nil."},
+  })
+}
+
+func TestPriorityQueue(t *testing.T) {
+  runAll(t, []expectation{
+    {"natural order", `var pqA = PriorityQueue(); pqA.push(3); pqA.push(1); pqA.push(2); print pqA.peek(); print pqA.toList(); print pqA.size();`, "1\n[1, 2, 3]\n3\n", ""},
+    {"pop", `var pqB = PriorityQueue(); pqB.push("b"); pqB.push("a"); print pqB.pop(); print pqB.pop(); print pqB.size();`, "a\nb\n0\n", ""},
+    {"comparator", `fun pqDescending(a, b) { return b - a; } var pqC = PriorityQueue(pqDescending); pqC.push(1); pqC.push(3); pqC.push(2); print pqC.toList();`, "[3, 2, 1]\n", ""},
+    {"comparator error", `fun pqBroken(a, b) { return nil.x; } var pqE = PriorityQueue(pqBroken); pqE.push(1); pqE.push(2);`, "", "Only instances have properties."},
+    {"incomparable items", `var pqF = PriorityQueue(); pqF.push(1); pqF.push("a");`, "", "Can't compare"},
+    {"comparator must be a function", `PriorityQueue(1);`, "", "Comparator must be a function, not number."},
+    {"pop from empty", `PriorityQueue().pop();`, "", "Can't pop from an empty priority queue."},
+    {"peek at empty", `PriorityQueue().peek();`, "", "Can't peek at an empty priority queue."},
+  })
+}
+
+func TestCollectionSubclasses(t *testing.T) {
+  runAll(t, []expectation{
+    {"inherits native methods", `class CsStack < Deque { top() { return this.peekBack(); } } var csA = CsStack(); csA.pushBack(5); print csA.top(); print csA is Deque;`, "5\ntrue\n", ""},
+    {"init calls super", `class CsBag < Set { init(xs) { super.init(xs); this.label = "bag"; } } var csB = CsBag([1, 1]); print csB.size(); print csB.label;`, "1\nbag\n", ""},
+    {"bound method in a variable", `var csSet = Set(); var csAdd = csSet.add; csAdd(1); print csSet.has(1);`, "true\n", ""},
+  })
+}
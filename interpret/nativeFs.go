@@ -0,0 +1,192 @@
+package interpret
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"lox/environment"
+	"os"
+	"sort"
+	"strings"
+)
+
+type fileHandle struct {
+  path string
+  file *os.File
+  reader *bufio.Reader
+  closed bool
+}
+
+func (f *fileHandle) String() string {
+  return "<file " + f.path + ">"
+}
+
+// ioError reports a failed operation with the path it was given, dropping
+// Go's own copy of the path from the message.
+func ioError(operation string, path string, err error) error {
+  var pathError *fs.PathError
+  if errors.As(err, &pathError) {err = pathError.Err}
+  return fmt.Errorf("Could not %s '%s': %s.", operation, path, err)
+}
+
+func init() {
+  registerModule("fs", map[string]any{
+    "readFile": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("readFile", arguments, 0)
+      if err != nil {return nil, err}
+      content, err := os.ReadFile(path)
+      if err != nil {return nil, ioError("read", path, err)}
+      return string(content), nil
+    }),
+
+    "writeFile": native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("writeFile", arguments, 0)
+      if err != nil {return nil, err}
+      content, err := stringArgument("writeFile", arguments, 1)
+      if err != nil {return nil, err}
+      err = os.WriteFile(path, []byte(content), 0644)
+      if err != nil {return nil, ioError("write", path, err)}
+      return nil, nil
+    }),
+
+    "appendFile": native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("appendFile", arguments, 0)
+      if err != nil {return nil, err}
+      content, err := stringArgument("appendFile", arguments, 1)
+      if err != nil {return nil, err}
+      file, err := os.OpenFile(path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
+      if err != nil {return nil, ioError("open", path, err)}
+      defer file.Close()
+      _, err = file.WriteString(content)
+      if err != nil {return nil, ioError("append to", path, err)}
+      return nil, nil
+    }),
+
+    "exists": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("exists", arguments, 0)
+      if err != nil {return nil, err}
+      _, err = os.Stat(path)
+      if errors.Is(err, fs.ErrNotExist) {return false, nil}
+      if err != nil {return nil, ioError("stat", path, err)}
+      return true, nil
+    }),
+
+    "remove": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("remove", arguments, 0)
+      if err != nil {return nil, err}
+      err = os.Remove(path)
+      if err != nil {return nil, ioError("remove", path, err)}
+      return nil, nil
+    }),
+
+    "listDir": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("listDir", arguments, 0)
+      if err != nil {return nil, err}
+      entries, err := os.ReadDir(path)
+      if err != nil {return nil, ioError("list", path, err)}
+
+      var names []string
+      for _, entry := range entries {
+        names = append(names, entry.Name())
+      }
+      sort.Strings(names)
+      return stringList(names), nil
+    }),
+
+    "mkdir": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("mkdir", arguments, 0)
+      if err != nil {return nil, err}
+      err = os.MkdirAll(path, 0755)
+      if err != nil {return nil, ioError("create directory", path, err)}
+      return nil, nil
+    }),
+
+    "stat": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("stat", arguments, 0)
+      if err != nil {return nil, err}
+      info, err := os.Stat(path)
+      if err != nil {return nil, ioError("stat", path, err)}
+      return record(map[string]any{
+        "name": info.Name(),
+        "size": float64(info.Size()),
+        "isDir": info.IsDir(),
+        "modified": float64(info.ModTime().UnixMilli()) / 1000,
+      }), nil
+    }),
+
+    "rename": native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+      from, err := stringArgument("rename", arguments, 0)
+      if err != nil {return nil, err}
+      to, err := stringArgument("rename", arguments, 1)
+      if err != nil {return nil, err}
+      err = os.Rename(from, to)
+      if err != nil {return nil, ioError("rename", from, err)}
+      return nil, nil
+    }),
+
+    "open": native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+      path, err := stringArgument("open", arguments, 0)
+      if err != nil {return nil, err}
+      mode := "r"
+      if len(arguments) > 1 {
+        mode, err = stringArgument("open", arguments, 1)
+        if err != nil {return nil, err}
+      }
+
+      var flags int
+      switch mode {
+      case "r":
+        flags = os.O_RDONLY
+      case "w":
+        flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+      case "a":
+        flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+      default:
+        return nil, fmt.Errorf("Unknown file mode '%s'; expected \"r\", \"w\" or \"a\".", mode)
+      }
+      file, err := os.OpenFile(path, flags, 0644)
+      if err != nil {return nil, ioError("open", path, err)}
+      return &fileHandle{path, file, bufio.NewReader(file), false}, nil
+    }),
+  })
+
+  registerMethods("file", methodTable{
+    Getters: map[string]func(receiver any) (any, error){
+      "path": func(receiver any) (any, error) {
+        return receiver.(*fileHandle).path, nil
+      },
+    },
+    Methods: map[string]primitiveMethod{
+      // readLine returns nil once the file is exhausted.
+      "readLine": fileMethod(0, 0, func(f *fileHandle, arguments []any) (any, error) {
+        line, err := f.reader.ReadString('\n')
+        if err == io.EOF && line == "" {return nil, nil}
+        if err != nil && err != io.EOF {return nil, ioError("read", f.path, err)}
+        return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+      }),
+      "write": fileMethod(1, 1, func(f *fileHandle, arguments []any) (any, error) {
+        text, err := stringArgument("write", arguments, 0)
+        if err != nil {return nil, err}
+        _, err = f.file.WriteString(text)
+        if err != nil {return nil, ioError("write", f.path, err)}
+        return nil, nil
+      }),
+      "close": fileMethod(0, 0, func(f *fileHandle, arguments []any) (any, error) {
+        f.closed = true
+        err := f.file.Close()
+        if err != nil {return nil, ioError("close", f.path, err)}
+        return nil, nil
+      }),
+    },
+  })
+}
+
+func fileMethod(min int, max int, call func(f *fileHandle, arguments []any) (any, error)) primitiveMethod {
+  return primitiveMethod{min, max, func(env environment.Environment, receiver any, arguments []any) (any, error) {
+    f := receiver.(*fileHandle)
+    if f.closed {return nil, fmt.Errorf("File '%s' is already closed.", f.path)}
+    return call(f, arguments)
+  }}
+}
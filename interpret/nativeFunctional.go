@@ -0,0 +1,211 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"sort"
+)
+
+// sortStable sorts elements in place, keeping equal elements in order. The
+// first error from compare stops the sort and is returned.
+func sortStable[T any](elements []T, compare func(a T, b T) (int, error)) error {
+  var failed error
+  sort.SliceStable(elements, func(i, j int) bool {
+    if failed != nil {return false}
+    order, err := compare(elements[i], elements[j])
+    if err != nil {failed = err}
+    return order < 0
+  })
+  return failed
+}
+
+// Comparators return a negative number when their first argument goes first.
+func comparator(env environment.Environment, function any) func(a any, b any) (int, error) {
+  if function == nil {return compareValues}
+  return func(a any, b any) (int, error) {
+    result, err := callback(env, "comparator", function, []any{a, b})
+    if err != nil {return 0, err}
+    order, ok := result.(float64)
+    if !ok {return 0, fmt.Errorf("Comparator must return a number, not %s.", typeName(result))}
+    if order < 0 {return -1, nil}
+    if order > 0 {return 1, nil}
+    return 0, nil
+  }
+}
+
+// Without a predicate, any and all test the elements themselves.
+func truthOf(env environment.Environment, name string, arguments []any, element any) (bool, error) {
+  if len(arguments) < 2 {return isTruthy(element), nil}
+  result, err := callback(env, name, arguments[1], []any{element})
+  return isTruthy(result), err
+}
+
+func init() {
+  registerNative("map", native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("map", arguments, 0)
+    if err != nil {return nil, err}
+    mapped := &LoxList{}
+    for _, element := range elements {
+      value, err := callback(env, "map", arguments[1], []any{element})
+      if err != nil {return nil, err}
+      mapped.Elements = append(mapped.Elements, value)
+    }
+    return mapped, nil
+  }))
+
+  registerNative("filter", native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("filter", arguments, 0)
+    if err != nil {return nil, err}
+    kept := &LoxList{}
+    for _, element := range elements {
+      keep, err := callback(env, "filter", arguments[1], []any{element})
+      if err != nil {return nil, err}
+      if isTruthy(keep) {kept.Elements = append(kept.Elements, element)}
+    }
+    return kept, nil
+  }))
+
+  // Without an initial value, reduce starts from the first element.
+  registerNative("reduce", native(2, 3, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("reduce", arguments, 0)
+    if err != nil {return nil, err}
+    if len(arguments) < 3 {
+      if len(elements) == 0 {return nil, errors.New("Can't reduce an empty sequence without an initial value.")}
+      arguments = append(arguments, elements[0])
+      elements = elements[1:]
+    }
+
+    accumulated := arguments[2]
+    for _, element := range elements {
+      accumulated, err = callback(env, "reduce", arguments[1], []any{accumulated, element})
+      if err != nil {return nil, err}
+    }
+    return accumulated, nil
+  }))
+
+  registerNative("any", native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("any", arguments, 0)
+    if err != nil {return nil, err}
+    for _, element := range elements {
+      truth, err := truthOf(env, "any", arguments, element)
+      if err != nil || truth {return truth, err}
+    }
+    return false, nil
+  }))
+
+  registerNative("all", native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("all", arguments, 0)
+    if err != nil {return nil, err}
+    for _, element := range elements {
+      truth, err := truthOf(env, "all", arguments, element)
+      if err != nil || !truth {return truth, err}
+    }
+    return true, nil
+  }))
+
+  // zip stops at the end of its shortest argument.
+  registerNative("zip", native(1, -1, func(env environment.Environment, arguments []any) (any, error) {
+    sequences := make([][]any, len(arguments))
+    shortest := -1
+    for i := range arguments {
+      elements, err := sequenceArgument("zip", arguments, i)
+      if err != nil {return nil, err}
+      sequences[i] = elements
+      if shortest < 0 || len(elements) < shortest {shortest = len(elements)}
+    }
+
+    zipped := &LoxList{}
+    for i := 0; i < shortest; i++ {
+      tuple := LoxTuple{make([]any, len(sequences))}
+      for j, elements := range sequences {
+        tuple.Elements[j] = elements[i]
+      }
+      zipped.Elements = append(zipped.Elements, tuple)
+    }
+    return zipped, nil
+  }))
+
+  registerNative("enumerate", native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("enumerate", arguments, 0)
+    if err != nil {return nil, err}
+    start := 0
+    if len(arguments) > 1 {
+      start, err = integerArgument("enumerate", arguments, 1)
+      if err != nil {return nil, err}
+    }
+
+    enumerated := &LoxList{}
+    for i, element := range elements {
+      enumerated.Elements = append(enumerated.Elements, LoxTuple{[]any{float64(start + i), element}})
+    }
+    return enumerated, nil
+  }))
+
+  // sorted returns a new list ordered by the natural order of each element,
+  // or of the key function's result for it. Reversing keeps equal elements
+  // in their original order.
+  registerNative("sorted", native(1, 3, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("sorted", arguments, 0)
+    if err != nil {return nil, err}
+    var key any
+    if len(arguments) > 1 {key = arguments[1]}
+    reverse := len(arguments) > 2 && isTruthy(arguments[2])
+
+    keys := make([]any, len(elements))
+    order := make([]int, len(elements))
+    for i, element := range elements {
+      keys[i] = element
+      if key != nil {
+        keys[i], err = callback(env, "key", key, []any{element})
+        if err != nil {return nil, err}
+      }
+      order[i] = i
+    }
+
+    err = sortStable(order, func(a int, b int) (int, error) {
+      comparison, err := compareValues(keys[a], keys[b])
+      if reverse {comparison = -comparison}
+      return comparison, err
+    })
+    if err != nil {return nil, err}
+
+    result := &LoxList{make([]any, len(elements))}
+    for i, index := range order {
+      result.Elements[i] = elements[index]
+    }
+    return result, nil
+  }))
+
+  registerNative("sort", native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+    list, ok := arguments[0].(*LoxList)
+    if !ok {return nil, fmt.Errorf("Argument 1 of 'sort' must be a list, not %s.", typeName(arguments[0]))}
+    var function any
+    if len(arguments) > 1 {function = arguments[1]}
+
+    // Sorting a copy leaves the list untouched if a comparison fails.
+    elements := append([]any(nil), list.Elements...)
+    err := sortStable(elements, comparator(env, function))
+    if err != nil {return nil, err}
+    copy(list.Elements, elements)
+    return list, nil
+  }))
+
+  registerNative("groupBy", native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+    elements, err := sequenceArgument("groupBy", arguments, 0)
+    if err != nil {return nil, err}
+    groups := NewLoxMap()
+    for _, element := range elements {
+      key, err := callback(env, "groupBy", arguments[1], []any{element})
+      if err != nil {return nil, err}
+      group, ok, err := groups.Get(key)
+      if err != nil {return nil, err}
+      if !ok {
+        group = &LoxList{}
+        groups.Set(key, group)
+      }
+      group.(*LoxList).Elements = append(group.(*LoxList).Elements, element)
+    }
+    return groups, nil
+  }))
+}
@@ -0,0 +1,54 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+const functionalHelpers = `
+fun hfDouble(x) { return x * 2; }
+fun hfAdd(a, b) { return a + b; }
+fun hfDescending(a, b) { return b - a; }
+fun hfBroken(x) { return nil.x; }
+fun hfBrokenPair(a, b) { return nil.x; }
+fun hfWord(a, b) { return "w"; }
+fun hfLength(s) { return s.length; }
+`
+
+func TestHigherOrderNatives(t *testing.T) {
+  runAll(t, []expectation{
+    {"map", functionalHelpers + `print map([1, 2, 3], hfDouble);`, "[2, 4, 6]\n", ""},
+    {"map over a tuple and a set", functionalHelpers + `print map((1, 2), hfDouble); print map(Set([3]), hfDouble);`, "[2, 4]\n[6]\n", ""},
+    {"map with a native", `print map([-1, 2], math.abs);`, "[1, 2]\n", ""},
+    {"filter", `fun hfBig(x) { return x > 1; } print filter([1, 2, 3], hfBig);`, "[2, 3]\n", ""},
+    {"reduce", functionalHelpers + `print reduce([1, 2, 3], hfAdd); print reduce([1, 2], hfAdd, 10);`, "6\n13\n", ""},
+    {"reduce an empty list with a start", functionalHelpers + `print reduce([], hfAdd, 0);`, "0\n", ""},
+    {"any and all", `fun hfPositive(x) { return x > 0; } print any([false, nil]); print any([-1, 1], hfPositive); print all([1, true]); print all([1, -1], hfPositive);`, "false\ntrue\ntrue\nfalse\n", ""},
+    {"zip stops at the shortest", `print zip([1, 2, 3], ("a", "b"));`, "[(1, a), (2, b)]\n", ""},
+    {"enumerate", `print enumerate(["a", "b"]); print enumerate(["a"], 1);`, "[(0, a), (1, b)]\n[(1, a)]\n", ""},
+    {"sorted", `print sorted([3, 1, 2]); print sorted(["b", "a"]);`, "[1, 2, 3]\n[a, b]\n", ""},
+    {"sorted by key is stable", functionalHelpers + `print sorted(["bb", "a", "cc", "d"], hfLength);`, "[a, d, bb, cc]\n", ""},
+    {"sorted in reverse is stable", functionalHelpers + `print sorted(["bb", "a", "cc", "d"], hfLength, true);`, "[bb, cc, a, d]\n", ""},
+    {"sort in place", functionalHelpers + `var hfList = [1, 3, 2]; sort(hfList, hfDescending); print hfList;`, "[3, 2, 1]\n", ""},
+    {"groupBy", `fun hfFirst(s) { return s.substring(0, 1); } var hfGroups = groupBy(["ab", "ac", "b"], hfFirst); print hfGroups.get("a"); print hfGroups.keys();`, "[ab, ac]\n[a, b]\n", ""},
+  })
+}
+
+func TestCallbackErrors(t *testing.T) {
+  runAll(t, []expectation{
+    {"error out of map", functionalHelpers + `map([1], hfBroken);`, "", "Only instances have properties."},
+    {"error out of filter", functionalHelpers + `filter([1], hfBroken);`, "", "Only instances have properties."},
+    {"error out of reduce", functionalHelpers + `reduce([1, 2], hfBrokenPair);`, "", "Only instances have properties."},
+    {"error out of any", functionalHelpers + `any([1], hfBroken);`, "", "Only instances have properties."},
+    {"error out of a sort key", functionalHelpers + `sorted([1, 2], hfBroken);`, "", "Only instances have properties."},
+    {"error out of a comparator", functionalHelpers + `sort([1, 2], hfBrokenPair);`, "", "Only instances have properties."},
+    {"error out of groupBy", functionalHelpers + `groupBy([1], hfBroken);`, "", "Only instances have properties."},
+    {"comparator must return a number", functionalHelpers + `sort([1, 2], hfWord);`, "", "Comparator must return a number, not string."},
+    {"callback must be a function", `map([1], 2);`, "", "Expected 'map' to be a function, not number."},
+    {"callback arity", functionalHelpers + `map([1], hfAdd);`, "", "Missing argument for parameter 'b'."},
+    {"incomparable keys", `sorted([1, "a"]);`, "", "Can't compare"},
+    {"not a sequence", `map(1, clock);`, "", "Argument 1 of 'map' must be a list, tuple or collection, not number."},
+    {"sort needs a list", `sort((2, 1));`, "", "Argument 1 of 'sort' must be a list, not tuple."},
+    {"reduce an empty list", functionalHelpers + `reduce([], hfAdd);`, "", "Can't reduce an empty sequence without an initial value."},
+    {"bad enumerate start", `enumerate([1], 0.5);`, "", "Argument 2 of 'enumerate' must be an integer"},
+  })
+}
@@ -0,0 +1,234 @@
+package interpret
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lox/environment"
+	"lox/token"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+  registerModule("json", map[string]any{
+    "parse": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      text, err := stringArgument("parse", arguments, 0)
+      if err != nil {return nil, err}
+      return parseJSON(text)
+    }),
+
+    // The indent is a number of spaces or a string to indent with; without
+    // one the output is compact.
+    "stringify": native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+      indent := ""
+      if len(arguments) > 1 {
+        switch i := arguments[1].(type) {
+        case nil:
+        case string:
+          indent = i
+        case float64:
+          count, err := integerArgument("stringify", arguments, 1)
+          if err != nil {return nil, err}
+          if count > 0 {indent = strings.Repeat(" ", count)}
+        default:
+          return nil, fmt.Errorf("Argument 2 of 'stringify' must be a number or string, not %s.", typeName(arguments[1]))
+        }
+      }
+
+      encoder := jsonEncoder{env, indent, make(map[uintptr]bool), strings.Builder{}}
+      err := encoder.encode(arguments[0], 0)
+      if err != nil {return nil, err}
+      return encoder.builder.String(), nil
+    }),
+  })
+}
+
+// Objects decode to maps, which keep their keys in the order they appear in
+// the text.
+func parseJSON(text string) (any, error) {
+  decoder := json.NewDecoder(strings.NewReader(text))
+  value, err := decodeJSON(decoder)
+  if err != nil {return nil, jsonError(decoder, err)}
+
+  // Trailing data is reported where it starts, not after it.
+  end := decoder.InputOffset()
+  _, err = decoder.Token()
+  if err != io.EOF {
+    if err == nil {
+      rest := text[end:]
+      end += int64(len(rest) - len(strings.TrimLeft(rest, " \t\r\n")))
+      return nil, fmt.Errorf("Invalid JSON at byte %d: unexpected data after the top-level value.", end)
+    }
+    return nil, jsonError(decoder, err)
+  }
+  return value, nil
+}
+
+func jsonError(decoder *json.Decoder, err error) error {
+  offset := decoder.InputOffset()
+  var syntaxError *json.SyntaxError
+  if errors.As(err, &syntaxError) {
+    offset = syntaxError.Offset
+    if syntaxError.Error() == "unexpected end of JSON input" {err = io.ErrUnexpectedEOF}
+  }
+  if err == io.EOF || err == io.ErrUnexpectedEOF {err = errors.New("unexpected end of input")}
+  return fmt.Errorf("Invalid JSON at byte %d: %s.", offset, err)
+}
+
+func decodeJSON(decoder *json.Decoder) (any, error) {
+  tok, err := decoder.Token()
+  if err != nil {return nil, err}
+
+  switch tok {
+  case json.Delim('['):
+    list := &LoxList{}
+    for decoder.More() {
+      element, err := decodeJSON(decoder)
+      if err != nil {return nil, err}
+      list.Elements = append(list.Elements, element)
+    }
+    _, err = decoder.Token()
+    return list, err
+  case json.Delim('{'):
+    object := NewLoxMap()
+    for decoder.More() {
+      key, err := decoder.Token()
+      if err != nil {return nil, err}
+      value, err := decodeJSON(decoder)
+      if err != nil {return nil, err}
+      object.Set(key, value)
+    }
+    _, err = decoder.Token()
+    return object, err
+  }
+  return tok, nil
+}
+
+type jsonEncoder struct {
+  env environment.Environment
+  indent string
+  visiting map[uintptr]bool
+  builder strings.Builder
+}
+
+func (j *jsonEncoder) encode(value any, depth int) error {
+  switch v := value.(type) {
+  case nil:
+    j.builder.WriteString("null")
+  case bool:
+    j.builder.WriteString(strconv.FormatBool(v))
+  case float64:
+    if math.IsNaN(v) || math.IsInf(v, 0) {return fmt.Errorf("Can't convert %s to JSON.", Stringify(v))}
+    j.builder.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+  case string:
+    j.writeString(v)
+  case *LoxList:
+    return j.encodeArray(v, v.Elements, depth)
+  case LoxTuple:
+    return j.encodeArray(nil, v.Elements, depth)
+  case *LoxMap:
+    return j.enter(v, func() error {
+      var keys []string
+      for _, key := range v.keys {
+        name, ok := key.(string)
+        if !ok {return fmt.Errorf("JSON object keys must be strings, not %s.", typeName(key))}
+        keys = append(keys, name)
+      }
+      return j.encodeObject(keys, func(key string) any {
+        return v.values[key]
+      }, depth)
+    })
+  case LoxInstance:
+    return j.enter(v.Fields, func() error {
+      if v.Has("toJSON") {
+        converted, err := callToJSON(j.env, v)
+        if err != nil {return err}
+        return j.encode(converted, depth)
+      }
+      elements, ok := collectionElements(v)
+      if ok {return j.encodeArray(nil, elements, depth)}
+
+      var keys []string
+      for key := range v.Fields {
+        keys = append(keys, key)
+      }
+      sort.Strings(keys)
+      return j.encodeObject(keys, func(key string) any {
+        return v.Fields[key]
+      }, depth)
+    })
+  default:
+    return fmt.Errorf("Can't convert a %s to JSON.", typeName(value))
+  }
+  return nil
+}
+
+// enter marks a mutable container as being encoded for the duration of
+// encode, so that reaching it again means the structure is cyclic.
+func (j *jsonEncoder) enter(container any, encode func() error) error {
+  pointer := reflect.ValueOf(container).Pointer()
+  if j.visiting[pointer] {return errors.New("Can't convert a cyclic structure to JSON.")}
+  j.visiting[pointer] = true
+  defer delete(j.visiting, pointer)
+  return encode()
+}
+
+func (j *jsonEncoder) encodeArray(list *LoxList, elements []any, depth int) error {
+  encode := func() error {
+    j.builder.WriteString("[")
+    for i, element := range elements {
+      if i > 0 {j.builder.WriteString(",")}
+      j.newline(depth + 1)
+      err := j.encode(element, depth + 1)
+      if err != nil {return err}
+    }
+    if len(elements) > 0 {j.newline(depth)}
+    j.builder.WriteString("]")
+    return nil
+  }
+  if list == nil {return encode()}
+  return j.enter(list, encode)
+}
+
+func (j *jsonEncoder) encodeObject(keys []string, get func(key string) any, depth int) error {
+  j.builder.WriteString("{")
+  for i, key := range keys {
+    if i > 0 {j.builder.WriteString(",")}
+    j.newline(depth + 1)
+    j.writeString(key)
+    j.builder.WriteString(":")
+    if j.indent != "" {j.builder.WriteString(" ")}
+    err := j.encode(get(key), depth + 1)
+    if err != nil {return err}
+  }
+  if len(keys) > 0 {j.newline(depth)}
+  j.builder.WriteString("}")
+  return nil
+}
+
+func (j *jsonEncoder) newline(depth int) {
+  if j.indent == "" {return}
+  j.builder.WriteString("\n")
+  j.builder.WriteString(strings.Repeat(j.indent, depth))
+}
+
+func (j *jsonEncoder) writeString(s string) {
+  buffer := bytes.Buffer{}
+  encoder := json.NewEncoder(&buffer)
+  encoder.SetEscapeHTML(false)
+  encoder.Encode(s)
+  j.builder.WriteString(strings.TrimSuffix(buffer.String(), "\n"))
+}
+
+func callToJSON(env environment.Environment, instance LoxInstance) (any, error) {
+  name := token.Token{token.IDENTIFIER, "toJSON", nil, 0, 0}
+  method, err := instance.Get(name)
+  if err != nil {return nil, detach(err, name)}
+  return callback(env, "toJSON", method, nil)
+}
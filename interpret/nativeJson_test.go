@@ -0,0 +1,34 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+  runAll(t, []expectation{
+    {"parse scalars and arrays", `print json.parse("[1, true, null, 2.5, []]");`, "[1, true, nil, 2.5, []]\n", ""},
+    {"compact", `var jsA = Map(); jsA.set("b", 1); jsA.set("a", [true, nil]); print json.stringify(jsA);`, "{\"b\":1,\"a\":[true,null]}\n", ""},
+    {"indented", `var jsB = Map(); jsB.set("k", [1]); print json.stringify(jsB, 2);`, "{\n  \"k\": [\n    1\n  ]\n}\n", ""},
+    {"indent string", `print json.stringify([1], "--");`, "[\n--1\n]\n", ""},
+    {"round trip keeps key order", `var jsC = Map(); jsC.set("z", 1); jsC.set("a", 2); print json.parse(json.stringify(jsC)).keys();`, "[z, a]\n", ""},
+    {"tuple", `print json.stringify((1, "two"));`, "[1,\"two\"]\n", ""},
+    {"instance fields sorted", `class JsPoint { init() { this.y = 1; this.x = 2; } } print json.stringify(JsPoint());`, "{\"x\":2,\"y\":1}\n", ""},
+    {"toJSON", `class JsCustom { toJSON() { return [1]; } } print json.stringify(JsCustom());`, "[1]\n", ""},
+    {"native collection", `var jsSet = Set(); jsSet.add(1); print json.stringify(jsSet);`, "[1]\n", ""},
+    {"shared but not cyclic", `var jsShared = [1]; print json.stringify([jsShared, jsShared]);`, "[[1],[1]]\n", ""},
+    {"cyclic list", `var jsL = [1, 2]; jsL[1] = jsL; json.stringify(jsL);`, "", "Can't convert a cyclic structure to JSON."},
+    {"cyclic map", `var jsM = Map(); jsM.set("self", jsM); json.stringify(jsM);`, "", "Can't convert a cyclic structure to JSON."},
+    {"cyclic instance", `class JsNode {} var jsN = JsNode(); jsN.next = jsN; json.stringify(jsN);`, "", "Can't convert a cyclic structure to JSON."},
+    {"non-string key", `var jsK = Map(); jsK.set(1, 2); json.stringify(jsK);`, "", "JSON object keys must be strings, not number."},
+    {"function", `json.stringify(clock);`, "", "Can't convert a native to JSON."},
+    {"infinity", `json.stringify(math.INF);`, "", "Can't convert +Inf to JSON."},
+    {"bad indent", `json.stringify(1, true);`, "", "Argument 2 of 'stringify' must be a number or string, not bool."},
+    {"toJSON error", `class JsBroken { toJSON() { return nil.x; } } json.stringify(JsBroken());`, "", "Only instances have properties."},
+    {"parse needs a string", `json.parse(1);`, "", "Argument 1 of 'parse' must be a string, not number."},
+    {"unexpected end", `json.parse("[1, 2");`, "", "Invalid JSON at byte 5: unexpected end of input."},
+    {"empty input", `json.parse("");`, "", "Invalid JSON at byte 0: unexpected end of input."},
+    {"bad character", `json.parse("[1, x]");`, "", "Invalid JSON at byte 5: invalid character 'x' looking for beginning of value."},
+    {"trailing data", `json.parse("[1]  2");`, "", "Invalid JSON at byte 5: unexpected data after the top-level value."},
+    {"trailing whitespace", `print json.parse("[1]  ");`, "[1]\n", ""},
+  })
+}
@@ -0,0 +1,101 @@
+package interpret
+
+import (
+	"errors"
+	"lox/environment"
+	"math"
+)
+
+func init() {
+  members := map[string]any{
+    "PI": math.Pi,
+    "E": math.E,
+    "INF": math.Inf(1),
+    "NAN": math.NaN(),
+
+    "pow": binaryMath("pow", math.Pow),
+    "atan2": binaryMath("atan2", math.Atan2),
+    "hypot": binaryMath("hypot", math.Hypot),
+
+    "min": native(1, -1, func(env environment.Environment, arguments []any) (any, error) {
+      return foldMath("min", arguments, math.Min)
+    }),
+    "max": native(1, -1, func(env environment.Environment, arguments []any) (any, error) {
+      return foldMath("max", arguments, math.Max)
+    }),
+
+    "clamp": native(3, 3, func(env environment.Environment, arguments []any) (any, error) {
+      var bounds [3]float64
+      for i := range bounds {
+        number, err := numberArgument("clamp", arguments, i)
+        if err != nil {return nil, err}
+        bounds[i] = number
+      }
+      if bounds[1] > bounds[2] {return nil, errors.New("Lower bound of 'clamp' is greater than the upper bound.")}
+      return math.Max(bounds[1], math.Min(bounds[0], bounds[2])), nil
+    }),
+
+    "isNaN": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      number, ok := arguments[0].(float64)
+      return ok && math.IsNaN(number), nil
+    }),
+    "isFinite": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      number, ok := arguments[0].(float64)
+      return ok && !math.IsNaN(number) && !math.IsInf(number, 0), nil
+    }),
+  }
+
+  unary := map[string]func(float64) float64{
+    "sqrt": math.Sqrt,
+    "cbrt": math.Cbrt,
+    "abs": math.Abs,
+    "floor": math.Floor,
+    "ceil": math.Ceil,
+    "round": math.Round,
+    "trunc": math.Trunc,
+    "sin": math.Sin,
+    "cos": math.Cos,
+    "tan": math.Tan,
+    "asin": math.Asin,
+    "acos": math.Acos,
+    "atan": math.Atan,
+    "exp": math.Exp,
+    "log": math.Log,
+    "log2": math.Log2,
+    "log10": math.Log10,
+  }
+  for name, function := range unary {
+    members[name] = unaryMath(name, function)
+  }
+
+  registerModule("math", members)
+}
+
+func unaryMath(name string, function func(float64) float64) ProtoLoxCallable {
+  return native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+    x, err := numberArgument(name, arguments, 0)
+    if err != nil {return nil, err}
+    return function(x), nil
+  })
+}
+
+func binaryMath(name string, function func(float64, float64) float64) ProtoLoxCallable {
+  return native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+    x, err := numberArgument(name, arguments, 0)
+    if err != nil {return nil, err}
+    y, err := numberArgument(name, arguments, 1)
+    if err != nil {return nil, err}
+    return function(x, y), nil
+  })
+}
+
+func foldMath(name string, arguments []any, function func(float64, float64) float64) (any, error) {
+  result, err := numberArgument(name, arguments, 0)
+  if err != nil {return nil, err}
+  for i := 1; i < len(arguments); i++ {
+    number, err := numberArgument(name, arguments, i)
+    if err != nil {return nil, err}
+    result = function(result, number)
+  }
+  return result, nil
+}
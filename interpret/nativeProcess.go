@@ -0,0 +1,75 @@
+package interpret
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"lox/environment"
+	"os"
+	"strings"
+)
+
+// Args holds the command-line arguments that follow the script path.
+var Args []string
+
+// ExitCode is set once a script calls exit, and stays -1 otherwise.
+var ExitCode = -1
+
+// ExitError unwinds the whole program, running deferred calls on the way out,
+// until Interpret stops at the top level.
+type ExitError struct {
+  Code int
+}
+func (e ExitError) Error() string {
+  return "exit"
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readInput returns nil at the end of input.
+func readInput() (any, error) {
+  line, err := stdin.ReadString('\n')
+  if err == io.EOF && line == "" {return nil, nil}
+  if err != nil && err != io.EOF {return nil, fmt.Errorf("Could not read input: %s.", err)}
+  return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func init() {
+  registerNative("input", native(0, 1, func(env environment.Environment, arguments []any) (any, error) {
+    if len(arguments) > 0 {fmt.Print(Stringify(arguments[0]))}
+    return readInput()
+  }))
+
+  registerNative("readLine", native(0, 0, func(env environment.Environment, arguments []any) (any, error) {
+    return readInput()
+  }))
+
+  registerNative("exit", native(0, 1, func(env environment.Environment, arguments []any) (any, error) {
+    if len(arguments) == 0 {return nil, ExitError{0}}
+    code, err := integerArgument("exit", arguments, 0)
+    if err != nil {return nil, err}
+    if code < 0 || code > 255 {return nil, errors.New("Exit code must be between 0 and 255.")}
+    return nil, ExitError{code}
+  }))
+
+  registerModule("env", map[string]any{
+    "get": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      name, err := stringArgument("get", arguments, 0)
+      if err != nil {return nil, err}
+      value, ok := os.LookupEnv(name)
+      if !ok {return nil, nil}
+      return value, nil
+    }),
+
+    "set": native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+      name, err := stringArgument("set", arguments, 0)
+      if err != nil {return nil, err}
+      value, err := stringArgument("set", arguments, 1)
+      if err != nil {return nil, err}
+      err = os.Setenv(name, value)
+      if err != nil {return nil, fmt.Errorf("Could not set environment variable '%s': %s.", name, err)}
+      return nil, nil
+    }),
+  })
+}
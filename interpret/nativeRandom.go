@@ -0,0 +1,153 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type loxRandom struct {
+  *rand.Rand
+}
+
+func (r loxRandom) String() string {
+  return "<random>"
+}
+
+// The module's own functions draw from a shared generator, which --seed
+// reseeds before the script starts.
+var defaultRandom = loxRandom{rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func SeedRandom(seed int64) {
+  defaultRandom.Seed(seed)
+}
+
+func randomMethods() map[string]primitiveMethod {
+  return map[string]primitiveMethod{
+    "seed": randomMethod(1, 1, func(r loxRandom, arguments []any) (any, error) {
+      seed, err := integerArgument("seed", arguments, 0)
+      if err != nil {return nil, err}
+      r.Seed(int64(seed))
+      return nil, nil
+    }),
+
+    "random": randomMethod(0, 0, func(r loxRandom, arguments []any) (any, error) {
+      return r.Float64(), nil
+    }),
+
+    "uniform": randomMethod(2, 2, func(r loxRandom, arguments []any) (any, error) {
+      a, err := numberArgument("uniform", arguments, 0)
+      if err != nil {return nil, err}
+      b, err := numberArgument("uniform", arguments, 1)
+      if err != nil {return nil, err}
+      return a + (b - a) * r.Float64(), nil
+    }),
+
+    // randint includes both of its bounds.
+    "randint": randomMethod(2, 2, func(r loxRandom, arguments []any) (any, error) {
+      a, err := integerArgument("randint", arguments, 0)
+      if err != nil {return nil, err}
+      b, err := integerArgument("randint", arguments, 1)
+      if err != nil {return nil, err}
+      if a > b {return nil, fmt.Errorf("Empty range for 'randint': %d is greater than %d.", a, b)}
+      // The range's size must itself fit in an int.
+      span := b - a
+      if span < 0 || span == math.MaxInt {
+        return nil, fmt.Errorf("Range for 'randint' from %d to %d is too large.", a, b)
+      }
+      return float64(a + r.Intn(span + 1)), nil
+    }),
+
+    "choice": randomMethod(1, 1, func(r loxRandom, arguments []any) (any, error) {
+      elements, err := sequenceArgument("choice", arguments, 0)
+      if err != nil {return nil, err}
+      if len(elements) == 0 {return nil, errors.New("Can't choose from an empty sequence.")}
+      return elements[r.Intn(len(elements))], nil
+    }),
+
+    "shuffle": randomMethod(1, 1, func(r loxRandom, arguments []any) (any, error) {
+      list, ok := arguments[0].(*LoxList)
+      if !ok {return nil, fmt.Errorf("Argument 1 of 'shuffle' must be a list, not %s.", typeName(arguments[0]))}
+      r.Shuffle(len(list.Elements), func(i, j int) {
+        list.Elements[i], list.Elements[j] = list.Elements[j], list.Elements[i]
+      })
+      return list, nil
+    }),
+
+    // sample picks k distinct elements without changing the sequence.
+    "sample": randomMethod(2, 2, func(r loxRandom, arguments []any) (any, error) {
+      elements, err := sequenceArgument("sample", arguments, 0)
+      if err != nil {return nil, err}
+      k, err := integerArgument("sample", arguments, 1)
+      if err != nil {return nil, err}
+      if k < 0 || k > len(elements) {
+        return nil, fmt.Errorf("Sample size %d is out of range for %d elements.", k, len(elements))
+      }
+
+      sample := &LoxList{}
+      for _, i := range r.Perm(len(elements))[:k] {
+        sample.Elements = append(sample.Elements, elements[i])
+      }
+      return sample, nil
+    }),
+
+    "gauss": randomMethod(0, 2, func(r loxRandom, arguments []any) (any, error) {
+      mean, deviation, err := distributionArguments("gauss", arguments, 0, 1)
+      if err != nil {return nil, err}
+      return mean + deviation * r.NormFloat64(), nil
+    }),
+
+    "exponential": randomMethod(0, 1, func(r loxRandom, arguments []any) (any, error) {
+      rate, _, err := distributionArguments("exponential", arguments, 1, 0)
+      if err != nil {return nil, err}
+      if rate <= 0 {return nil, errors.New("Rate of 'exponential' must be positive.")}
+      return r.ExpFloat64() / rate, nil
+    }),
+  }
+}
+
+// distributionArguments reads up to two optional parameters.
+func distributionArguments(name string, arguments []any, first float64, second float64) (float64, float64, error) {
+  parameters := []float64{first, second}
+  for i := range arguments {
+    number, err := numberArgument(name, arguments, i)
+    if err != nil {return 0, 0, err}
+    parameters[i] = number
+  }
+  return parameters[0], parameters[1], nil
+}
+
+func init() {
+  methods := randomMethods()
+  registerMethods("random", methodTable{nil, methods})
+
+  members := map[string]any{
+    "Random": native(0, 1, func(env environment.Environment, arguments []any) (any, error) {
+      // Unseeded generators are seeded from the shared one, so --seed makes
+      // them repeatable too.
+      seed := defaultRandom.Int63()
+      if len(arguments) > 0 {
+        given, err := integerArgument("Random", arguments, 0)
+        if err != nil {return nil, err}
+        seed = int64(given)
+      }
+      return loxRandom{rand.New(rand.NewSource(seed))}, nil
+    }),
+  }
+  for name, method := range methods {
+    method := method
+    members[name] = native(method.min, method.max, func(env environment.Environment, arguments []any) (any, error) {
+      return method.call(env, defaultRandom, arguments)
+    })
+  }
+  registerModule("random", members)
+}
+
+func randomMethod(min int, max int, call func(r loxRandom, arguments []any) (any, error)) primitiveMethod {
+  return primitiveMethod{min, max, func(env environment.Environment, receiver any, arguments []any) (any, error) {
+    return call(receiver.(loxRandom), arguments)
+  }}
+}
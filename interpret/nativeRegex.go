@@ -0,0 +1,145 @@
+package interpret
+
+import (
+	"fmt"
+	"lox/environment"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+type loxRegex struct {
+  *regexp.Regexp
+}
+
+func (r loxRegex) String() string {
+  return "<regex " + r.Regexp.String() + ">"
+}
+
+// Compiled patterns are shared by every compile of the same source.
+var regexCache = make(map[string]loxRegex)
+
+func compileRegex(pattern string) (loxRegex, error) {
+  compiled, ok := regexCache[pattern]
+  if ok {return compiled, nil}
+
+  r, err := regexp.Compile(pattern)
+  if err != nil {return loxRegex{}, fmt.Errorf("Invalid regular expression: %s.", err)}
+  compiled = loxRegex{r}
+  regexCache[pattern] = compiled
+  return compiled, nil
+}
+
+// A match is reported with character, not byte, offsets, like string
+// indices. Unmatched groups are nil.
+func matchRecord(r loxRegex, s string, indices []int) LoxInstance {
+  groups := &LoxList{}
+  named := NewLoxMap()
+  for group := 1; group < len(indices) / 2; group++ {
+    var text any
+    if indices[2 * group] >= 0 {text = s[indices[2 * group]:indices[2 * group + 1]]}
+    groups.Elements = append(groups.Elements, text)
+
+    name := r.SubexpNames()[group]
+    if name != "" {named.Set(name, text)}
+  }
+
+  return record(map[string]any{
+    "text": s[indices[0]:indices[1]],
+    "start": float64(utf8.RuneCountInString(s[:indices[0]])),
+    "end": float64(utf8.RuneCountInString(s[:indices[1]])),
+    "groups": groups,
+    "named": named,
+  })
+}
+
+func regexMethods() map[string]primitiveMethod {
+  return map[string]primitiveMethod{
+    "test": regexMethod(1, 1, func(env environment.Environment, r loxRegex, arguments []any) (any, error) {
+      s, err := stringArgument("test", arguments, 0)
+      if err != nil {return nil, err}
+      return r.MatchString(s), nil
+    }),
+
+    "find": regexMethod(1, 1, func(env environment.Environment, r loxRegex, arguments []any) (any, error) {
+      s, err := stringArgument("find", arguments, 0)
+      if err != nil {return nil, err}
+      indices := r.FindStringSubmatchIndex(s)
+      if indices == nil {return nil, nil}
+      return matchRecord(r, s, indices), nil
+    }),
+
+    "findAll": regexMethod(1, 1, func(env environment.Environment, r loxRegex, arguments []any) (any, error) {
+      s, err := stringArgument("findAll", arguments, 0)
+      if err != nil {return nil, err}
+      matches := &LoxList{}
+      for _, indices := range r.FindAllStringSubmatchIndex(s, -1) {
+        matches.Elements = append(matches.Elements, matchRecord(r, s, indices))
+      }
+      return matches, nil
+    }),
+
+    // A string replacement may refer to groups as $1 or ${name}; a function
+    // is called with each match and returns its replacement.
+    "replace": regexMethod(2, 2, func(env environment.Environment, r loxRegex, arguments []any) (any, error) {
+      s, err := stringArgument("replace", arguments, 0)
+      if err != nil {return nil, err}
+      replacement, ok := arguments[1].(string)
+      if ok {return r.ReplaceAllString(s, replacement), nil}
+
+      builder := strings.Builder{}
+      last := 0
+      for _, indices := range r.FindAllStringSubmatchIndex(s, -1) {
+        value, err := callback(env, "replace", arguments[1], []any{matchRecord(r, s, indices)})
+        if err != nil {return nil, err}
+        builder.WriteString(s[last:indices[0]])
+        builder.WriteString(Stringify(value))
+        last = indices[1]
+      }
+      builder.WriteString(s[last:])
+      return builder.String(), nil
+    }),
+
+    "split": regexMethod(1, 2, func(env environment.Environment, r loxRegex, arguments []any) (any, error) {
+      s, err := stringArgument("split", arguments, 0)
+      if err != nil {return nil, err}
+      limit := -1
+      if len(arguments) > 1 {
+        limit, err = integerArgument("split", arguments, 1)
+        if err != nil {return nil, err}
+      }
+      return stringList(r.Split(s, limit)), nil
+    }),
+  }
+}
+
+func init() {
+  registerMethods("regex", methodTable{
+    Getters: map[string]func(receiver any) (any, error){
+      "source": func(receiver any) (any, error) {
+        return receiver.(loxRegex).Regexp.String(), nil
+      },
+    },
+    Methods: regexMethods(),
+  })
+
+  registerModule("re", map[string]any{
+    "compile": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      pattern, err := stringArgument("compile", arguments, 0)
+      if err != nil {return nil, err}
+      return compileRegex(pattern)
+    }),
+
+    "escape": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      text, err := stringArgument("escape", arguments, 0)
+      if err != nil {return nil, err}
+      return regexp.QuoteMeta(text), nil
+    }),
+  })
+}
+
+func regexMethod(min int, max int, call func(env environment.Environment, r loxRegex, arguments []any) (any, error)) primitiveMethod {
+  return primitiveMethod{min, max, func(env environment.Environment, receiver any, arguments []any) (any, error) {
+    return call(env, receiver.(loxRegex), arguments)
+  }}
+}
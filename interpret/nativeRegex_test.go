@@ -0,0 +1,30 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestRegex(t *testing.T) {
+  runAll(t, []expectation{
+    {"test", `var reA = re.compile("^\d+$"); print reA.test("123"); print reA.test("12a");`, "true\nfalse\n", ""},
+    {"source and printing", `var reB = re.compile("a+"); print reB.source; print reB;`, "a+\n<regex a+>\n", ""},
+    {"type", `print type(re.compile("a"));`, "regex\n", ""},
+    {"find", `var reC = re.compile("(\w+)@(\w+)").find("mail bob@home now"); print reC.text; print reC.start; print reC.end; print reC.groups;`, "bob@home\n5\n13\n[bob, home]\n", ""},
+    {"no match", `print re.compile("z").find("abc");`, "nil\n", ""},
+    {"character offsets", `var reD = re.compile("b").find("ééb"); print reD.start; print reD.end;`, "2\n3\n", ""},
+    {"named and unmatched groups", `var reE = re.compile("(?P<word>a)(b)?").find("a"); print reE.named.get("word"); print reE.groups;`, "a\n[a, nil]\n", ""},
+    {"findAll", `fun reText(m) { return m.text; } print map(re.compile("\d").findAll("a1b2c3"), reText);`, "[1, 2, 3]\n", ""},
+    {"replace with a string", `print re.compile("(\w+)=(\w+)").replace("a=b c=d", "$2=$1");`, "b=a d=c\n", ""},
+    {"replace with a function", `fun reDouble(m) { return m.text.length * 2; } print re.compile("\d+").replace("1 and 20", reDouble);`, "2 and 4\n", ""},
+    {"split", `print re.compile(",\s*").split("a, b,c");`, "[a, b, c]\n", ""},
+    {"split with a limit", `print re.compile(",").split("a,b,c", 2);`, "[a, b,c]\n", ""},
+    {"escape", `print re.escape("a.b*c"); print re.compile(re.escape("a.b")).test("axb");`, "a\\.b\\*c\nfalse\n", ""},
+    {"compiles are cached", `print re.compile("x") == re.compile("x");`, "true\n", ""},
+    {"invalid pattern", `re.compile("(");`, "", "Invalid regular expression: error parsing regexp: missing closing ): `(`."},
+    {"pattern must be a string", `re.compile(1);`, "", "Argument 1 of 'compile' must be a string, not number."},
+    {"subject must be a string", `re.compile("a").test(1);`, "", "Argument 1 of 'test' must be a string, not number."},
+    {"replacement callback error", `fun reBroken(m) { return nil.x; } re.compile("a").replace("a", reBroken);`, "", "Only instances have properties."},
+    {"replacement callback arity", `fun reTwo(x, y) { return x; } re.compile("a").replace("a", reTwo);`, "", "Missing argument for parameter 'y'."},
+    {"bad split limit", `re.compile(",").split("a,b", 1.5);`, "", "Argument 2 of 'split' must be an integer"},
+  })
+}
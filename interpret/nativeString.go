@@ -0,0 +1,201 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// String indices count characters rather than bytes.
+func init() {
+  registerMethods("string", methodTable{
+    Getters: map[string]func(receiver any) (any, error){
+      "length": func(receiver any) (any, error) {
+        return float64(utf8.RuneCountInString(receiver.(string))), nil
+      },
+    },
+    Methods: map[string]primitiveMethod{
+      "upper": stringMethod(0, 0, func(s string, arguments []any) (any, error) {
+        return strings.ToUpper(s), nil
+      }),
+      "lower": stringMethod(0, 0, func(s string, arguments []any) (any, error) {
+        return strings.ToLower(s), nil
+      }),
+      "trim": stringMethod(0, 0, func(s string, arguments []any) (any, error) {
+        return strings.TrimSpace(s), nil
+      }),
+
+      "split": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        separator, err := stringArgument("split", arguments, 0)
+        if err != nil {return nil, err}
+        return stringList(strings.Split(s, separator)), nil
+      }),
+      "join": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        elements, err := sequenceArgument("join", arguments, 0)
+        if err != nil {return nil, err}
+        parts := make([]string, len(elements))
+        for i, element := range elements {
+          parts[i] = Stringify(element)
+        }
+        return strings.Join(parts, s), nil
+      }),
+      "replace": stringMethod(2, 2, func(s string, arguments []any) (any, error) {
+        old, err := stringArgument("replace", arguments, 0)
+        if err != nil {return nil, err}
+        replacement, err := stringArgument("replace", arguments, 1)
+        if err != nil {return nil, err}
+        return strings.ReplaceAll(s, old, replacement), nil
+      }),
+      "startsWith": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        prefix, err := stringArgument("startsWith", arguments, 0)
+        return strings.HasPrefix(s, prefix), err
+      }),
+      "endsWith": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        suffix, err := stringArgument("endsWith", arguments, 0)
+        return strings.HasSuffix(s, suffix), err
+      }),
+      "contains": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        part, err := stringArgument("contains", arguments, 0)
+        return strings.Contains(s, part), err
+      }),
+      "indexOf": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        part, err := stringArgument("indexOf", arguments, 0)
+        if err != nil {return nil, err}
+        i := strings.Index(s, part)
+        if i < 0 {return float64(-1), nil}
+        return float64(utf8.RuneCountInString(s[:i])), nil
+      }),
+
+      "substring": stringMethod(1, 2, func(s string, arguments []any) (any, error) {
+        chars := []rune(s)
+        start, err := integerArgument("substring", arguments, 0)
+        if err != nil {return nil, err}
+        end := len(chars)
+        if len(arguments) > 1 {
+          end, err = integerArgument("substring", arguments, 1)
+          if err != nil {return nil, err}
+        }
+        if start < 0 || end > len(chars) || start > end {
+          return nil, fmt.Errorf("Substring range [%d, %d) is out of bounds for a string of length %d.", start, end, len(chars))
+        }
+        return string(chars[start:end]), nil
+      }),
+      "repeat": stringMethod(1, 1, func(s string, arguments []any) (any, error) {
+        count, err := integerArgument("repeat", arguments, 0)
+        if err != nil {return nil, err}
+        if count < 0 {return nil, errors.New("Repeat count can't be negative.")}
+        return repeatString("repeat", s, count)
+      }),
+      "chars": stringMethod(0, 0, func(s string, arguments []any) (any, error) {
+        return stringList(strings.Split(s, "")), nil
+      }),
+      "padStart": stringMethod(1, 2, func(s string, arguments []any) (any, error) {
+        padding, err := padding("padStart", s, arguments)
+        return padding + s, err
+      }),
+      "padEnd": stringMethod(1, 2, func(s string, arguments []any) (any, error) {
+        padding, err := padding("padEnd", s, arguments)
+        return s + padding, err
+      }),
+      "toNumber": stringMethod(0, 0, func(s string, arguments []any) (any, error) {
+        number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+        if err != nil {return nil, nil}
+        return number, nil
+      }),
+      "format": stringMethod(0, -1, func(s string, arguments []any) (any, error) {
+        return format(s, arguments)
+      }),
+    },
+  })
+}
+
+func stringMethod(min int, max int, call func(s string, arguments []any) (any, error)) primitiveMethod {
+  return primitiveMethod{min, max, func(env environment.Environment, receiver any, arguments []any) (any, error) {
+    return call(receiver.(string), arguments)
+  }}
+}
+
+func stringList(parts []string) *LoxList {
+  list := &LoxList{}
+  for _, part := range parts {
+    list.Elements = append(list.Elements, part)
+  }
+  return list
+}
+
+func sequenceArgument(name string, arguments []any, i int) ([]any, error) {
+  elements, ok := collectionElements(arguments[i])
+  if ok {return elements, nil}
+  return nil, fmt.Errorf("Argument %d of '%s' must be a list, tuple or collection, not %s.", i + 1, name, typeName(arguments[i]))
+}
+
+// padding builds the fill needed to bring s up to the requested width, by
+// repeating the pad string (a space by default) and cutting it to fit.
+func padding(name string, s string, arguments []any) (string, error) {
+  width, err := integerArgument(name, arguments, 0)
+  if err != nil {return "", err}
+  pad := " "
+  if len(arguments) > 1 {
+    pad, err = stringArgument(name, arguments, 1)
+    if err != nil {return "", err}
+    if pad == "" {return "", fmt.Errorf("Pad string of '%s' can't be empty.", name)}
+  }
+
+  missing := width - utf8.RuneCountInString(s)
+  if missing <= 0 {return "", nil}
+  runes := utf8.RuneCountInString(pad)
+  fill, err := repeatString(name, pad, (missing + runes - 1) / runes)
+  if err != nil {return "", err}
+  return string([]rune(fill)[:missing]), nil
+}
+
+// maxStringLength bounds the strings natives build by repetition, so a huge
+// count is reported rather than exhausting memory.
+const maxStringLength = 1 << 30
+
+func repeatString(name string, s string, count int) (string, error) {
+  if len(s) > 0 && count > maxStringLength / len(s) {
+    return "", fmt.Errorf("Result of '%s' would be longer than %d bytes.", name, maxStringLength)
+  }
+  return strings.Repeat(s, count), nil
+}
+
+// format replaces each '{}' with the next argument and each '{n}' with the
+// nth one. '{{' and '}}' stand for literal braces.
+func format(template string, arguments []any) (string, error) {
+  builder := strings.Builder{}
+  next := 0
+  for i := 0; i < len(template); i++ {
+    c := template[i]
+    if (c == '{' || c == '}') && i + 1 < len(template) && template[i + 1] == c {
+      builder.WriteByte(c)
+      i++
+      continue
+    }
+    if c != '{' {
+      builder.WriteByte(c)
+      continue
+    }
+
+    end := strings.IndexByte(template[i:], '}')
+    if end < 0 {return "", errors.New("Unterminated '{' in format string.")}
+    field := template[i + 1 : i + end]
+    index := next
+    if field == "" {
+      next++
+    } else {
+      n, err := strconv.Atoi(field)
+      if err != nil {return "", fmt.Errorf("Invalid format field '{%s}'.", field)}
+      index = n
+    }
+    if index < 0 || index >= len(arguments) {
+      return "", fmt.Errorf("Format field %d is out of range for %d arguments.", index, len(arguments))
+    }
+    builder.WriteString(Stringify(arguments[index]))
+    i += end
+  }
+  return builder.String(), nil
+}
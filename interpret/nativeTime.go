@@ -0,0 +1,169 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"time"
+	_ "time/tzdata"
+)
+
+// Durations are plain numbers of seconds, which a date's add method takes and
+// its sub method returns; dates themselves don't work with '+' or '<'. Dates
+// are equal, and make the same map key, when they are the same instant.
+type loxDate struct {
+  time.Time
+}
+
+func (d loxDate) String() string {
+  return d.Format(time.RFC3339Nano)
+}
+
+var started = time.Now()
+
+func seconds(d time.Duration) float64 {
+  return d.Seconds()
+}
+
+func duration(s float64) time.Duration {
+  return time.Duration(s * float64(time.Second))
+}
+
+func locationArgument(name string, arguments []any, i int) (*time.Location, error) {
+  if i >= len(arguments) || arguments[i] == nil {return time.UTC, nil}
+  zone, err := stringArgument(name, arguments, i)
+  if err != nil {return nil, err}
+  location, err := time.LoadLocation(zone)
+  if err != nil {return nil, fmt.Errorf("Unknown time zone '%s'.", zone)}
+  return location, nil
+}
+
+func dateArgument(name string, arguments []any, i int) (loxDate, error) {
+  date, ok := arguments[i].(loxDate)
+  if !ok {
+    return loxDate{}, fmt.Errorf("Argument %d of '%s' must be a date, not %s.", i + 1, name, typeName(arguments[i]))
+  }
+  return date, nil
+}
+
+func init() {
+  registerModule("time", map[string]any{
+    "RFC3339": time.RFC3339,
+    "DATE": time.DateOnly,
+    "TIME": time.TimeOnly,
+    "DATETIME": time.DateTime,
+
+    // now is monotonic: it only ever moves forward, whatever happens to the
+    // wall clock.
+    "now": native(0, 0, func(env environment.Environment, arguments []any) (any, error) {
+      return seconds(time.Since(started)), nil
+    }),
+
+    "current": native(0, 1, func(env environment.Environment, arguments []any) (any, error) {
+      location, err := locationArgument("current", arguments, 0)
+      if err != nil {return nil, err}
+      return loxDate{time.Now().In(location)}, nil
+    }),
+
+    // date takes the year, month and day, then optionally the hour, minute,
+    // second and time zone, which defaults to UTC.
+    "date": native(3, 7, func(env environment.Environment, arguments []any) (any, error) {
+      var parts [6]int
+      for i := 0; i < len(parts) && i < len(arguments); i++ {
+        part, err := integerArgument("date", arguments, i)
+        if err != nil {return nil, err}
+        parts[i] = part
+      }
+      location, err := locationArgument("date", arguments, 6)
+      if err != nil {return nil, err}
+      return loxDate{time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, location)}, nil
+    }),
+
+    "fromUnix": native(1, 2, func(env environment.Environment, arguments []any) (any, error) {
+      unix, err := numberArgument("fromUnix", arguments, 0)
+      if err != nil {return nil, err}
+      location, err := locationArgument("fromUnix", arguments, 1)
+      if err != nil {return nil, err}
+      return loxDate{time.Unix(0, int64(unix * 1e9)).In(location)}, nil
+    }),
+
+    "parse": native(2, 3, func(env environment.Environment, arguments []any) (any, error) {
+      layout, err := stringArgument("parse", arguments, 0)
+      if err != nil {return nil, err}
+      text, err := stringArgument("parse", arguments, 1)
+      if err != nil {return nil, err}
+      location, err := locationArgument("parse", arguments, 2)
+      if err != nil {return nil, err}
+      parsed, err := time.ParseInLocation(layout, text, location)
+      if err != nil {return nil, fmt.Errorf("Could not parse '%s' as a date: %s.", text, err)}
+      return loxDate{parsed}, nil
+    }),
+
+    "duration": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      text, err := stringArgument("duration", arguments, 0)
+      if err != nil {return nil, err}
+      parsed, err := time.ParseDuration(text)
+      if err != nil {return nil, fmt.Errorf("Invalid duration '%s'.", text)}
+      return seconds(parsed), nil
+    }),
+
+    "sleep": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      s, err := numberArgument("sleep", arguments, 0)
+      if err != nil {return nil, err}
+      if s < 0 {return nil, errors.New("Can't sleep for a negative duration.")}
+      time.Sleep(duration(s))
+      return nil, nil
+    }),
+  })
+
+  getters := map[string]func(d time.Time) any{
+    "year": func(d time.Time) any {return float64(d.Year())},
+    "month": func(d time.Time) any {return float64(d.Month())},
+    "day": func(d time.Time) any {return float64(d.Day())},
+    "hour": func(d time.Time) any {return float64(d.Hour())},
+    "minute": func(d time.Time) any {return float64(d.Minute())},
+    "second": func(d time.Time) any {return float64(d.Second())},
+    "millisecond": func(d time.Time) any {return float64(d.Nanosecond() / 1e6)},
+    "weekday": func(d time.Time) any {return d.Weekday().String()},
+    "yearDay": func(d time.Time) any {return float64(d.YearDay())},
+    "zone": func(d time.Time) any {return d.Location().String()},
+    "unix": func(d time.Time) any {return float64(d.UnixNano()) / 1e9},
+  }
+  table := methodTable{make(map[string]func(receiver any) (any, error)), nil}
+  for name, getter := range getters {
+    getter := getter
+    table.Getters[name] = func(receiver any) (any, error) {
+      return getter(receiver.(loxDate).Time), nil
+    }
+  }
+
+  table.Methods = map[string]primitiveMethod{
+    "format": dateMethod(1, 1, func(d loxDate, arguments []any) (any, error) {
+      layout, err := stringArgument("format", arguments, 0)
+      if err != nil {return nil, err}
+      return d.Format(layout), nil
+    }),
+    "add": dateMethod(1, 1, func(d loxDate, arguments []any) (any, error) {
+      s, err := numberArgument("add", arguments, 0)
+      if err != nil {return nil, err}
+      return loxDate{d.Add(duration(s))}, nil
+    }),
+    "sub": dateMethod(1, 1, func(d loxDate, arguments []any) (any, error) {
+      other, err := dateArgument("sub", arguments, 0)
+      if err != nil {return nil, err}
+      return seconds(d.Sub(other.Time)), nil
+    }),
+    "in": dateMethod(1, 1, func(d loxDate, arguments []any) (any, error) {
+      location, err := locationArgument("in", arguments, 0)
+      if err != nil {return nil, err}
+      return loxDate{d.In(location)}, nil
+    }),
+  }
+  registerMethods("date", table)
+}
+
+func dateMethod(min int, max int, call func(d loxDate, arguments []any) (any, error)) primitiveMethod {
+  return primitiveMethod{min, max, func(env environment.Environment, receiver any, arguments []any) (any, error) {
+    return call(receiver.(loxDate), arguments)
+  }}
+}
@@ -0,0 +1,15 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestDateEquality(t *testing.T) {
+  runAll(t, []expectation{
+    {"same date in a named zone", `print time.date(2024, 2, 29, 12, 0, 0, "America/New_York") == time.date(2024, 2, 29, 12, 0, 0, "America/New_York");`, "true\n", ""},
+    {"same instant in another zone", `var d = time.date(2024, 2, 29, 12, 0, 0, "America/New_York"); print d == d.in("UTC");`, "true\n", ""},
+    {"different instants", `print time.date(2024, 2, 29, 12, 0, 0) == time.date(2024, 2, 29, 12, 0, 1);`, "false\n", ""},
+    {"map key", `var m = Map(); m.set(time.date(2024, 1, 1, 0, 0, 0, "Europe/Paris"), "new year"); print m.get(time.date(2024, 1, 1, 0, 0, 0, "Europe/Paris"));`, "new year\n", ""},
+    {"no arithmetic operators", `print time.date(2024, 1, 1, 0, 0, 0) + 1;`, "", "Operands must be"},
+  })
+}
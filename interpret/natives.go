@@ -0,0 +1,126 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"lox/token"
+	"time"
+)
+
+// Natives register themselves from init functions and are defined as globals
+// once, before the first program runs, so a REPL line can shadow them.
+var natives = make(map[string]any)
+
+func registerNative(name string, value any) {
+  natives[name] = value
+}
+
+// A module is a frozen instance whose fields are its members.
+func registerModule(name string, members map[string]any) {
+  natives[name] = record(members)
+}
+
+func record(fields map[string]any) LoxInstance {
+  frozen := true
+  return LoxInstance{nil, fields, &frozen, nil, nil}
+}
+
+// DefineNatives defines the natives and args in GlobalEnv. Call it after
+// setting Args.
+func DefineNatives() {
+  for name, value := range natives {
+    environment.Define(&GlobalEnv, name, value)
+  }
+  environment.Define(&GlobalEnv, "args", stringList(Args))
+}
+
+func native(min int, max int, call func(env environment.Environment, arguments []any) (any, error)) ProtoLoxCallable {
+  return ProtoLoxCallable{
+    callMethod: call,
+    arityMethod: func() (int, int) {
+      return min, max
+    },
+    stringMethod: func() string {
+      return "<native fn>"
+    },
+  }
+}
+
+// Errors returned by natives are reported at the call's closing paren.
+func numberArgument(name string, arguments []any, i int) (float64, error) {
+  number, ok := arguments[i].(float64)
+  if !ok {
+    return 0, fmt.Errorf("Argument %d of '%s' must be a number, not %s.", i + 1, name, typeName(arguments[i]))
+  }
+  return number, nil
+}
+
+func integerArgument(name string, arguments []any, i int) (int, error) {
+  number, err := numberArgument(name, arguments, i)
+  if err != nil {return 0, err}
+  if number != float64(int(number)) {
+    return 0, fmt.Errorf("Argument %d of '%s' must be an integer.", i + 1, name)
+  }
+  return int(number), nil
+}
+
+func stringArgument(name string, arguments []any, i int) (string, error) {
+  text, ok := arguments[i].(string)
+  if !ok {
+    return "", fmt.Errorf("Argument %d of '%s' must be a string, not %s.", i + 1, name, typeName(arguments[i]))
+  }
+  return text, nil
+}
+
+// NewNative makes a Lox callable from a Go function, which is passed between
+// min and max arguments. A max of -1 allows any number.
+func NewNative(min int, max int, call func(env environment.Environment, arguments []any) (any, error)) ProtoLoxCallable {
+  return native(min, max, call)
+}
+
+// RegisterNative defines a global for every program run after it.
+func RegisterNative(name string, value any) {
+  registerNative(name, value)
+}
+
+// CallFunction lets a native call back into Lox. Any callable can be passed,
+// and calls may nest. A runtime error raised inside the function is returned
+// unchanged, so it is still reported where it happened; a plain Go error
+// returned to the interpreter is reported at the native's call site.
+func CallFunction(env environment.Environment, function any, arguments ...any) (any, error) {
+  return callback(env, "function", function, arguments)
+}
+
+// callback calls a function value handed to a native, checking its arity as
+// a call in Lox would.
+func callback(env environment.Environment, name string, value any, arguments []any) (any, error) {
+  function, ok := value.(LoxCallable)
+  if !ok {return nil, fmt.Errorf("Expected '%s' to be a function, not %s.", name, typeName(value))}
+  tok := token.Token{token.IDENTIFIER, name, nil, 0, 0}
+  err := checkArity(tok, function, len(arguments))
+  if err != nil {return nil, detach(err, tok)}
+  return function.Call(env, arguments)
+}
+
+func init() {
+  registerNative("clock", native(0, 0, func(env environment.Environment, arguments []any) (any, error) {
+    return float64(time.Now().UnixNano()) / 1e9, nil
+  }))
+
+  registerNative("freeze", native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+    switch object := arguments[0].(type) {
+    case LoxInstance:
+      *object.frozen = true
+    case LoxClass:
+      *object.frozen = true
+    default:
+      return nil, errors.New("Can only freeze instances and classes.")
+    }
+    return arguments[0], nil
+  }))
+
+  registerNative("type", native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+    return typeName(arguments[0]), nil
+  }))
+}
@@ -0,0 +1,13 @@
+package interpret_test
+
+import (
+	"testing"
+)
+
+func TestGlobalsShadowNativesAcrossRuns(t *testing.T) {
+  runAll(t, []expectation{
+    {"shadow a native", `var map = 1;`, "", ""},
+    {"later run sees the shadow", `print map;`, "1\n", ""},
+    {"other natives are untouched", `print type(clock);`, "native\n", ""},
+  })
+}
@@ -0,0 +1,57 @@
+package interpret
+
+import (
+	"lox/environment"
+	"lox/token"
+)
+
+type Pattern interface {
+  AstPrint() string
+  VisitPattern(env environment.Environment, value any, bind binder) error
+}
+
+type binder func(name token.Token, value any) error
+
+type NamePattern struct {
+  Name token.Token
+  Default Expr
+  Type TypeExpr
+}
+
+type ListPattern struct {
+  Bracket token.Token
+  Elements []Pattern
+  Rest *token.Token
+  Default Expr
+}
+
+type ObjectPattern struct {
+  Brace token.Token
+  Properties []PropertyPattern
+  Rest *token.Token
+  Default Expr
+}
+
+type PropertyPattern struct {
+  Key token.Token
+  Value Pattern
+}
+
+func patternNames(pattern Pattern) []token.Token {
+  var names []token.Token
+  switch p := pattern.(type) {
+  case NamePattern:
+    names = append(names, p.Name)
+  case ListPattern:
+    for _, element := range p.Elements {
+      names = append(names, patternNames(element)...)
+    }
+    if p.Rest != nil {names = append(names, *p.Rest)}
+  case ObjectPattern:
+    for _, property := range p.Properties {
+      names = append(names, patternNames(property.Value)...)
+    }
+    if p.Rest != nil {names = append(names, *p.Rest)}
+  }
+  return names
+}
@@ -0,0 +1,48 @@
+package interpret
+
+import (
+	"fmt"
+	"lox/environment"
+	"lox/loxError"
+	"lox/token"
+)
+
+// Values without fields of their own, such as strings, can still have
+// properties: each type named by typeName may register a table of getters and
+// methods, and Get binds them to the receiver.
+type primitiveMethod struct {
+  min int
+  max int
+  call func(env environment.Environment, receiver any, arguments []any) (any, error)
+}
+
+type methodTable struct {
+  Getters map[string]func(receiver any) (any, error)
+  Methods map[string]primitiveMethod
+}
+
+var methodTables = make(map[string]methodTable)
+
+func registerMethods(typeName string, table methodTable) {
+  methodTables[typeName] = table
+}
+
+func getPrimitive(object any, name token.Token) (any, error) {
+  table, ok := methodTables[typeName(object)]
+  if !ok {return nil, loxError.RuntimeError{name, "Only instances have properties."}}
+
+  getter, ok := table.Getters[name.Lexeme]
+  if ok {
+    value, err := getter(object)
+    if err != nil {return nil, loxError.RuntimeError{name, err.Error()}}
+    return value, nil
+  }
+
+  method, ok := table.Methods[name.Lexeme]
+  if !ok {
+    return nil, loxError.RuntimeError{name, fmt.Sprintf("Undefined property '%s' on %s.", name.Lexeme, typeName(object))}
+  }
+  return native(method.min, method.max, func(env environment.Environment, arguments []any) (any, error) {
+    return method.call(env, object, arguments)
+  }), nil
+}
@@ -0,0 +1,55 @@
+package interpret
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"lox/environment"
+)
+
+// A comparator that fails part way through must leave the queue untouched.
+func TestPriorityQueueFailedComparison(t *testing.T) {
+  failing := false
+  compare := native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+    if failing {return nil, errors.New("comparison failed")}
+    return arguments[0].(float64) - arguments[1].(float64), nil
+  })
+
+  q := &priorityQueueState{comparator: compare}
+  for _, item := range []float64{5, 3, 8, 1, 4} {
+    err := q.push(GlobalEnv, item)
+    if err != nil {t.Fatal(err)}
+  }
+  before := append([]any(nil), q.items...)
+
+  failing = true
+  if q.push(GlobalEnv, 0.0) == nil {t.Error("push succeeded despite the failing comparator")}
+  if !reflect.DeepEqual(q.items, before) {t.Errorf("push left %v, want %v", q.items, before)}
+  _, err := q.pop(GlobalEnv)
+  if err == nil {t.Error("pop succeeded despite the failing comparator")}
+  if !reflect.DeepEqual(q.items, before) {t.Errorf("pop left %v, want %v", q.items, before)}
+
+  failing = false
+  var popped []any
+  for len(q.items) > 0 {
+    item, err := q.pop(GlobalEnv)
+    if err != nil {t.Fatal(err)}
+    popped = append(popped, item)
+  }
+  want := []any{1.0, 3.0, 4.0, 5.0, 8.0}
+  if !reflect.DeepEqual(popped, want) {t.Errorf("popped %v, want %v", popped, want)}
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+  q := &priorityQueueState{}
+  for i := 1; i <= 30; i++ {
+    err := q.push(GlobalEnv, float64(i * 7 % 31))
+    if err != nil {t.Fatal(err)}
+  }
+  for want := 1.0; want <= 30; want++ {
+    item, err := q.pop(GlobalEnv)
+    if err != nil {t.Fatal(err)}
+    if item != want {t.Fatalf("popped %v, want %v", item, want)}
+  }
+}
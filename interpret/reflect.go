@@ -0,0 +1,172 @@
+package interpret
+
+import (
+	"errors"
+	"fmt"
+	"lox/environment"
+	"lox/loxError"
+	"lox/token"
+	"sort"
+)
+
+func init() {
+  registerModule("reflect", reflectModule())
+}
+
+func reflectModule() map[string]any {
+  return map[string]any{
+    "classOf": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      instance, ok := arguments[0].(LoxInstance)
+      if !ok || instance.Class == nil {return nil, nil}
+      return *instance.Class, nil
+    }),
+
+    "name": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      class, err := classArgument(arguments[0])
+      if err != nil {return nil, err}
+      return class.Name.Lexeme, nil
+    }),
+
+    "superclass": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      class, err := classArgument(arguments[0])
+      if err != nil {return nil, err}
+      if class.Superclass == nil {return nil, nil}
+      return *class.Superclass, nil
+    }),
+
+    "methods": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      class, err := classArgument(arguments[0])
+      if err != nil {return nil, err}
+      return sortedNames(class.Methods), nil
+    }),
+
+    "getters": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      class, err := classArgument(arguments[0])
+      if err != nil {return nil, err}
+      return sortedNames(class.Getters), nil
+    }),
+
+    "statics": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      class, err := classArgument(arguments[0])
+      if err != nil {return nil, err}
+      return sortedNames(class.Fields), nil
+    }),
+
+    "fields": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      instance, err := instanceArgument(arguments[0])
+      if err != nil {return nil, err}
+      return sortedNames(instance.Fields), nil
+    }),
+
+    "has": native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+      instance, err := instanceArgument(arguments[0])
+      if err != nil {return nil, err}
+      name, err := nameArgument(arguments[1])
+      if err != nil {return nil, err}
+      return instance.Has(name.Lexeme), nil
+    }),
+
+    "get": native(2, 2, func(env environment.Environment, arguments []any) (any, error) {
+      instance, err := instanceArgument(arguments[0])
+      if err != nil {return nil, err}
+      name, err := nameArgument(arguments[1])
+      if err != nil {return nil, err}
+      value, err := instance.Get(name)
+      return value, detach(err, name)
+    }),
+
+    "set": native(3, 3, func(env environment.Environment, arguments []any) (any, error) {
+      instance, err := instanceArgument(arguments[0])
+      if err != nil {return nil, err}
+      name, err := nameArgument(arguments[1])
+      if err != nil {return nil, err}
+      return arguments[2], detach(instance.Set(name, arguments[2]), name)
+    }),
+
+    "arity": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      function, ok := arguments[0].(LoxCallable)
+      if !ok {return nil, errors.New("Expected a function or class.")}
+
+      min, max := function.Arity()
+      var most any = float64(max)
+      if max < 0 {most = nil}
+      return LoxTuple{[]any{float64(min), most}}, nil
+    }),
+
+    "params": native(1, 1, func(env environment.Environment, arguments []any) (any, error) {
+      _, ok := arguments[0].(LoxCallable)
+      if !ok {return nil, errors.New("Expected a function or class.")}
+
+      var names []any
+      p, ok := arguments[0].(parameterized)
+      if ok {
+        for _, name := range p.parameterNames() {
+          names = append(names, name)
+        }
+      }
+      return &LoxList{names}, nil
+    }),
+
+    "invoke": native(2, -1, func(env environment.Environment, arguments []any) (any, error) {
+      instance, err := instanceArgument(arguments[0])
+      if err != nil {return nil, err}
+      name, err := nameArgument(arguments[1])
+      if err != nil {return nil, err}
+
+      method, err := instance.Get(name)
+      if err != nil {return nil, detach(err, name)}
+      function, ok := method.(LoxCallable)
+      if !ok {return nil, fmt.Errorf("Property '%s' is not callable.", name.Lexeme)}
+
+      err = checkArity(name, function, len(arguments) - 2)
+      if err != nil {return nil, detach(err, name)}
+      return function.Call(env, arguments[2:])
+    }),
+  }
+}
+
+func classArgument(value any) (LoxClass, error) {
+  class, ok := value.(LoxClass)
+  if !ok {return LoxClass{}, errors.New("Expected a class.")}
+  return class, nil
+}
+
+// Classes are accepted wherever an instance is, so their statics can be
+// reached the same way.
+func instanceArgument(value any) (LoxInstance, error) {
+  switch object := value.(type) {
+  case LoxInstance:
+    return object, nil
+  case LoxClass:
+    return object.LoxInstance, nil
+  }
+  return LoxInstance{}, errors.New("Expected an instance.")
+}
+
+func nameArgument(value any) (token.Token, error) {
+  name, ok := value.(string)
+  if !ok {return token.Token{}, errors.New("Property name must be a string.")}
+  return token.Token{token.IDENTIFIER, name, nil, 0, 0}, nil
+}
+
+// Errors raised against a made-up name token are reported at the native's
+// call site instead.
+func detach(err error, name token.Token) error {
+  rE, ok := err.(loxError.RuntimeError)
+  if ok && rE.Token == name {return errors.New(rE.Message)}
+  return err
+}
+
+func sortedNames[T any](members map[string]T) *LoxList {
+  var names []string
+  for name := range members {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  list := &LoxList{}
+  for _, name := range names {
+    list.Elements = append(list.Elements, name)
+  }
+  return list
+}
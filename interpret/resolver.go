@@ -7,12 +7,17 @@ import (
 	"lox/functionType"
 	"lox/loxError"
 	"lox/token"
+	"lox/typeKind"
 	"lox/varUsage"
 )
 
 type stack []map[string]varusage.VarUsage
 var currentFunction functiontype.FunctionType
 var currentClass classtype.ClassType = classtype.NONE
+var tailCallsAllowed bool
+// resultScope is the depth of the scope holding the implicit 'result' of a
+// function that defers calls, which nothing else may declare.
+var resultScope int
 
 func (s stack) Ack(name string, value varusage.VarUsage) {
   s[len(s)-1][name] = value
@@ -32,11 +37,16 @@ func (s stack) Pop() (stack, map[string]varusage.VarUsage) {
 }
 
 var scopes stack
+var constants []map[string]bool
 
 type Scope interface {
   VisitScope(env environment.Environment)
 }
 
+type PatternScope interface {
+  VisitPatternScope(env environment.Environment, bind func(token.Token))
+}
+
 func (e Block) VisitScope(env environment.Environment) {
   beginScope()
   Resolve(env, e.Statements)
@@ -44,11 +54,26 @@ func (e Block) VisitScope(env environment.Environment) {
 }
 
 func (e Class) VisitScope(env environment.Environment) {
-  enclosingClass := currentClass
-  currentClass = classtype.CLASS
-  
   declare(e.Name)
   define(e.Name)
+  shapeClass(e)
+  resolveClass(env, e)
+}
+
+func (e ClassExpr) VisitScope(env environment.Environment) {
+  resolveClass(env, e.Declaration)
+}
+
+func resolveClass(env environment.Environment, e Class) {
+  enclosingClass := currentClass
+  currentClass = classtype.CLASS
+  if e.Superclass != nil {currentClass = classtype.SUBCLASS}
+  enclosingName := analyzedClass
+  analyzedClass = classKey(e.Name)
+  enclosingStatic := inStaticMethod
+  inStaticMethod = false
+
+  resolveDecorators(env, e.Decorators)
 
   if e.Superclass != nil && e.Name.Lexeme == e.Superclass.Name.Lexeme {
     loxError.TokenError(e.Superclass.Name, "A class can't inherit from itself")
@@ -63,6 +88,17 @@ func (e Class) VisitScope(env environment.Environment) {
     scopes.Ack("super", varusage.INITIALIZED)
   }
 
+  for _, method := range e.Methods {
+    resolveDecorators(env, method.Decorators)
+  }
+  for _, method := range e.StaticMethods {
+    resolveDecorators(env, method.Decorators)
+  }
+  for _, nested := range e.Classes {
+    resolveClass(env, nested)
+  }
+  analyzedClass = classKey(e.Name)
+
   beginScope()
   scopes.Ack("this", varusage.INITIALIZED)
 
@@ -84,17 +120,46 @@ func (e Class) VisitScope(env environment.Environment) {
 
   endScope()
 
+  // Static methods are closed over the class environment, which has no 'this'.
+  inStaticMethod = true
+  for _, method := range e.StaticMethods {
+    resolveFunction(env, method, functiontype.METHOD)
+  }
+  inStaticMethod = enclosingStatic
+
   if e.Superclass != nil {endScope()}
   
   currentClass = enclosingClass
+  analyzedClass = enclosingName
+}
+
+func resolveDecorators(env environment.Environment, decorators []Decorator) {
+  for _, decorator := range decorators {
+    resolveExpr(env, decorator.Expression)
+  }
 }
 
 func (e Var) VisitScope(env environment.Environment) {
-  declare(e.Name)
+  for _, name := range patternNames(e.Pattern) {
+    declare(name)
+  }
   if e.Initializer != nil {
     resolveExpr(env, e.Initializer)
   }
-  define(e.Name)
+  resolvePattern(env, e.Pattern, define)
+
+  name, ok := e.Pattern.(NamePattern)
+  if ok {
+    value := staticType{kind: typekind.NIL}
+    if e.Initializer != nil {value = infer(e.Initializer)}
+    noteFact(name.Name.Lexeme, value)
+  }
+
+  if e.Constant && len(constants) != 0 {
+    for _, name := range patternNames(e.Pattern) {
+      constants[len(constants)-1][name.Lexeme] = true
+    }
+  }
 }
 
 func (e Variable) VisitScope(env environment.Environment) {
@@ -108,12 +173,77 @@ func (e Variable) VisitScope(env environment.Environment) {
 
 func (e Assign) VisitScope(env environment.Environment) {
   resolveExpr(env, e.Value)
-  resolveLocal(e, e.Name)
+  checkAssignable(e.Name)
+  resolveLocal(Variable{e.Name}, e.Name)
+  updateFact(e.Name.Lexeme, infer(e.Value))
+}
+
+func (e Destructure) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Value)
+  resolvePattern(env, e.Pattern, func(name token.Token) {
+    checkAssignable(name)
+    resolveLocal(Variable{name}, name)
+    updateFact(name.Lexeme, anyType)
+  })
+}
+
+func (e List) VisitScope(env environment.Environment) {
+  for _, element := range e.Elements {
+    resolveExpr(env, element)
+  }
+}
+
+func (e Tuple) VisitScope(env environment.Environment) {
+  for _, element := range e.Elements {
+    resolveExpr(env, element)
+  }
+}
+
+func (e Spread) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Expression)
+}
+
+func (e Index) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Object)
+  resolveExpr(env, e.Index)
+}
+
+func (e SetIndex) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Value)
+  resolveExpr(env, e.Object)
+  resolveExpr(env, e.Index)
+}
+
+func (e NamePattern) VisitPatternScope(env environment.Environment, bind func(token.Token)) {
+  if e.Default != nil {resolveExpr(env, e.Default)}
+  bind(e.Name)
+}
+
+func (e ListPattern) VisitPatternScope(env environment.Environment, bind func(token.Token)) {
+  if e.Default != nil {resolveExpr(env, e.Default)}
+  for _, element := range e.Elements {
+    resolvePattern(env, element, bind)
+  }
+  if e.Rest != nil {bind(*e.Rest)}
+}
+
+func (e ObjectPattern) VisitPatternScope(env environment.Environment, bind func(token.Token)) {
+  if e.Default != nil {resolveExpr(env, e.Default)}
+  for _, property := range e.Properties {
+    resolvePattern(env, property.Value, bind)
+  }
+  if e.Rest != nil {bind(*e.Rest)}
 }
 
 func (e Function) VisitScope(env environment.Environment) {
+  resolveDecorators(env, e.Decorators)
   declare(e.Name)
   define(e.Name)
+  if len(e.Decorators) > 0 {
+    noteFact(e.Name.Lexeme, anyType)
+  } else {
+    noteFact(e.Name.Lexeme, staticType{kind: typekind.FUNCTION, signature: shapeSignature(e)})
+  }
 
   resolveFunction(env, e, functiontype.FUNCTION)
 }
@@ -124,14 +254,68 @@ func (e Expression) VisitScope(env environment.Environment) {
 
 func (e If) VisitScope(env environment.Environment) {
   resolveExpr(env, e.Condition)
+
+  before := snapshotFacts()
   resolveStmt(env, e.ThenBranch)
-  if e.ElseBranch != nil {resolveStmt(env, e.ThenBranch)}
+  after := snapshotFacts()
+
+  facts = before
+  if e.ElseBranch != nil {resolveStmt(env, e.ElseBranch)}
+  joinFacts(after, snapshotFacts())
 }
 
 func (e Print) VisitScope(env environment.Environment) {
   resolveExpr(env, e.Expression)
 }
 
+func (e Assert) VisitScope(env environment.Environment) {
+  resolveContracts(env, []Contract{Contract(e)})
+}
+
+func (e Old) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Expression)
+}
+
+func resolveContracts(env environment.Environment, clauses []Contract) {
+  for _, clause := range clauses {
+    resolveExpr(env, clause.Condition)
+    if clause.Message != nil {resolveExpr(env, clause.Message)}
+  }
+}
+
+func (e Defer) VisitScope(env environment.Environment) {
+  if currentFunction == functiontype.NONE {
+    loxError.TokenError(e.Keyword, "Can't defer from top-level code.")
+  }
+  resolveExpr(env, e.Call)
+}
+
+// Functions that defer calls get an implicit 'result' local holding the value
+// being returned, which the deferred calls may read or replace.
+func defers(statements []Stmt) bool {
+  for _, statement := range statements {
+    switch s := statement.(type) {
+    case Defer:
+      return true
+    case Block:
+      if defers(s.Statements) {return true}
+    case If:
+      if defers([]Stmt{s.ThenBranch}) {return true}
+      if s.ElseBranch != nil && defers([]Stmt{s.ElseBranch}) {return true}
+    case While:
+      if defers([]Stmt{s.Body}) {return true}
+      if s.Else != nil && defers([]Stmt{s.Else}) {return true}
+    case DoWhile:
+      if defers([]Stmt{s.Body}) {return true}
+    case Switch:
+      for _, c := range s.Cases {
+        if defers(c.Body) {return true}
+      }
+    }
+  }
+  return false
+}
+
 func (e Return) VisitScope(env environment.Environment) {
   if currentFunction == functiontype.NONE {
     loxError.TokenError(e.Keyword, "Can't return from top-level code")
@@ -142,17 +326,80 @@ func (e Return) VisitScope(env environment.Environment) {
       loxError.TokenError(e.Keyword, "Can't return from an initializer.")
     }
     resolveExpr(env, e.Value)
+
+    call, ok := e.Value.(Call)
+    if ok && tailCallsAllowed {tailCalls[call.Paren] = true}
   }
 }
 
+// IsTailCall reports whether the resolver found call in tail position, where
+// it replaces the calling function's frame instead of growing the stack.
+func IsTailCall(call Call) bool {
+  return tailCalls[call.Paren]
+}
+
 func (e While) VisitScope(env environment.Environment) {
+  enclosingBarrier := factBarrier
+  factBarrier = len(scopes)
+
   resolveExpr(env, e.Condition)
   resolveStmt(env, e.Body)
+
+  factBarrier = enclosingBarrier
+  if e.Else != nil {resolveStmt(env, e.Else)}
+}
+
+func (e DoWhile) VisitScope(env environment.Environment) {
+  enclosingBarrier := factBarrier
+  factBarrier = len(scopes)
+
+  resolveStmt(env, e.Body)
+  resolveExpr(env, e.Condition)
+
+  factBarrier = enclosingBarrier
+}
+
+func (e Switch) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Subject)
+  for _, c := range e.Cases {
+    for _, value := range c.Values {
+      resolveExpr(env, value)
+    }
+  }
+
+  // Each case starts from what was known before the switch, joined with the
+  // end of the previous case when that one falls through.
+  before := snapshotFacts()
+  var after, carried []map[string]staticType
+  hasDefault := false
+  for _, c := range e.Cases {
+    if c.Values == nil {hasDefault = true}
+
+    facts = copyFacts(before)
+    if carried != nil {joinFacts(before, carried)}
+
+    beginScope()
+    Resolve(env, c.Body)
+    endScope()
+
+    end := snapshotFacts()
+    carried = nil
+    if c.Fallthrough {carried = end}
+    if after != nil {
+      joinFacts(after, end)
+      end = snapshotFacts()
+    }
+    after = end
+  }
+
+  if after != nil {facts = after}
+  if !hasDefault {joinFacts(before, snapshotFacts())}
 }
 
 func (e Binary) VisitScope(env environment.Environment) {
   resolveExpr(env, e.Left)
   resolveExpr(env, e.Right)
+  analyzeBinary(e)
 }
 
 func (e Call) VisitScope(env environment.Environment) {
@@ -161,34 +408,52 @@ func (e Call) VisitScope(env environment.Environment) {
   for _, argument := range e.Arguments {
     resolveExpr(env, argument)
   }
+  for _, named := range e.Named {
+    resolveExpr(env, named.Value)
+  }
+  analyzeCall(e)
 }
 
 func (e Get) VisitScope(env environment.Environment) {
   resolveExpr(env, e.Object)
+  analyzeGet(e)
 }
 
 func (e Grouping) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Expression)
 }
 
 func (e Literal) VisitScope(env environment.Environment) {
 }
 
 func (e Logical) VisitScope(env environment.Environment) {
+  resolveExpr(env, e.Left)
   resolveExpr(env, e.Right)
 }
 
 func (e Set) VisitScope(env environment.Environment) {
   resolveExpr(env, e.Value)
   resolveExpr(env, e.Object)
+  analyzeSet(e)
 }
 
 func (e Super) VisitScope(env environment.Environment) {
+  if currentClass == classtype.NONE {
+    loxError.TokenError(e.Keyword, "Can't use 'super' outside of a class.")
+  } else if currentClass != classtype.SUBCLASS {
+    loxError.TokenError(e.Keyword, "Can't use 'super' in a class with no superclass.")
+  } else if inStaticMethod {
+    loxError.TokenError(e.Keyword, "Can't use 'super' in a static method.")
+  }
+
   resolveLocal(e, e.Keyword)
 }
 
 func (e This) VisitScope(env environment.Environment) {
   if currentClass == classtype.NONE {
     loxError.TokenError(e.Keyword, "Can't use 'this' outside of a class")
+  } else if inStaticMethod {
+    loxError.Warning(e.Keyword, "'this' in a static method has no instance to refer to.")
   }
   
   resolveLocal(e, e.Keyword)
@@ -199,9 +464,11 @@ func (e Unary) VisitScope(env environment.Environment) {
 }
 
 func InitialResolve(env environment.Environment, statements []Stmt) {
+  beginAnalysis()
   beginScope()
   Resolve(env, statements)
   endScope()
+  finishAnalysis()
 }
 
 func Resolve(env environment.Environment, statements []Stmt) {
@@ -222,28 +489,62 @@ func resolveExpr(env environment.Environment, expr Expr) {
   s.VisitScope(env) 
 }
 
+func resolvePattern(env environment.Environment, pattern Pattern, bind func(token.Token)) {
+  s, _ := pattern.(PatternScope)
+  s.VisitPatternScope(env, bind)
+}
+
 func resolveFunction(env environment.Environment, function Function, typey functiontype.FunctionType) {
   enclosingFunction := currentFunction
   currentFunction = typey
+  enclosingBarrier := factBarrier
+  factBarrier = len(scopes)
+  enclosingTailCalls := tailCallsAllowed
+  tailCallsAllowed = typey != functiontype.INITIALIZER && !defers(function.Body) && len(function.Ensures) == 0
+  enclosingResult := resultScope
+  resultScope = 0
     
   beginScope()
+  if defers(function.Body) {
+    deferring[function.Name] = true
+    scopes.Ack("result", varusage.USED)
+    resultScope = len(scopes)
+  }
   for _, param := range function.Params {
-    declare(param)
-    define(param)
+    resolvePattern(env, param, func(name token.Token) {
+      declare(name)
+      define(name)
+    })
+  }
+  if function.Rest != nil {
+    declare(*function.Rest)
+    define(*function.Rest)
+  }
+  resolveContracts(env, function.Requires)
+  if len(function.Ensures) > 0 {
+    scopes.Ack("result", varusage.USED)
+    resolveContracts(env, function.Ensures)
   }
   Resolve(env, function.Body)
   endScope()
 
   currentFunction = enclosingFunction
+  factBarrier = enclosingBarrier
+  tailCallsAllowed = enclosingTailCalls
+  resultScope = enclosingResult
 }
 
 func beginScope() {
   scopes = scopes.Push(make(map[string]varusage.VarUsage))
+  constants = append(constants, make(map[string]bool))
+  facts = append(facts, make(map[string]staticType))
 }
 
 func endScope() {
   var scope map[string]varusage.VarUsage
   scopes, scope = scopes.Pop()
+  constants = constants[:len(constants)-1]
+  facts = facts[:len(facts)-1]
 
   for k, v := range scope {
     if v != varusage.USED && k != "this" {
@@ -255,7 +556,9 @@ func endScope() {
 func declare(name token.Token) {
  if len(scopes) == 0 {return} 
   _, ok := scopes.Peek()[name.Lexeme]
-  if ok {
+  if ok && name.Lexeme == "result" && len(scopes) == resultScope {
+    loxError.TokenError(name, "Can't declare 'result' in a function that defers calls; it holds the return value.")
+  } else if ok {
     loxError.TokenError(name, "Already a variable with this name in this scope.")
   }
   scopes.Ack(name.Lexeme, varusage.DECLARED)
@@ -266,6 +569,18 @@ func define(name token.Token) {
   scopes.Ack(name.Lexeme, varusage.INITIALIZED)
 }
 
+func checkAssignable(name token.Token) {
+  for i := len(scopes) - 1; i >= 0; i-- {
+    _, ok := scopes[i][name.Lexeme]
+    if ok {
+      if constants[i][name.Lexeme] {
+        loxError.TokenError(name, "Can't assign to constant '" + name.Lexeme + "'.")
+      }
+      return
+    }
+  }
+}
+
 func resolveLocal(expr Expr, name token.Token) {
   for i := len(scopes) - 1; i >= 0; i-- {
     _, ok := scopes[i][name.Lexeme]
@@ -7,6 +7,7 @@ import (
 
 type Stmt interface {
   VisitStmt(environment.Environment) error
+  AstPrint() string
 }
 
 type Block struct {
@@ -19,6 +20,14 @@ type Class struct {
   Methods []Function
   StaticMethods []Function
   Getters []Function
+  Fields []Field
+  Classes []Class
+  Decorators []Decorator
+}
+
+type Field struct {
+  Name token.Token
+  Type TypeExpr
 }
 
 type Expression struct {
@@ -27,8 +36,27 @@ type Expression struct {
 
 type Function struct {
   Name token.Token
-  Params []token.Token
+  Params []Pattern
+  Rest *token.Token
+  ReturnType TypeExpr
   Body []Stmt
+  Decorators []Decorator
+  Requires []Contract
+  Ensures []Contract
+  Olds []Old
+}
+
+// Source is the condition as written, for the error raised when it fails.
+type Contract struct {
+  Keyword token.Token
+  Condition Expr
+  Message Expr
+  Source string
+}
+
+type Decorator struct {
+  At token.Token
+  Expression Expr
 }
 
 type If struct {
@@ -47,14 +75,56 @@ type Return struct {
 }
 
 type Var struct {
-  Name token.Token
+  Pattern Pattern
   Initializer Expr
+  Constant bool
 }
 
 type While struct {
   Condition Expr
   Body Stmt
+  Else Stmt
+}
+
+type DoWhile struct {
+  Body Stmt
+  Condition Expr
+}
+
+type Switch struct {
+  Keyword token.Token
+  Subject Expr
+  Cases []SwitchCase
+}
+
+// A default case has no values. Fallthrough carries control into the body of
+// the next case without testing its values.
+type SwitchCase struct {
+  Keyword token.Token
+  Values []Expr
+  Body []Stmt
+  Fallthrough bool
 }
 
 type Break struct {
 }
+
+type Assert struct {
+  Keyword token.Token
+  Condition Expr
+  Message Expr
+  Source string
+}
+
+// Macros are expanded by the parser; the declaration is kept in the tree
+// only so it can be printed.
+type Macro struct {
+  Name token.Token
+  Params []token.Token
+  Body []token.Token
+}
+
+type Defer struct {
+  Keyword token.Token
+  Call Call
+}
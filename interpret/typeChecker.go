@@ -0,0 +1,925 @@
+package interpret
+
+import (
+	"fmt"
+	"lox/loxError"
+	"lox/token"
+	"lox/typeKind"
+	"strings"
+)
+
+// StrictTypes makes parameter and return annotations mandatory on every
+// function and method whose name does not start with an underscore.
+var StrictTypes = false
+
+// Only types that come from annotations are trusted enough to report errors
+// for, so unannotated code is left to the runtime.
+type staticType struct {
+  kind typekind.TypeKind
+  class string
+  nullable bool
+  signature *signature
+  declared bool
+}
+
+type signature struct {
+  params []staticType
+  required int
+  variadic bool
+  result staticType
+}
+
+type classInfo struct {
+  superclass string
+  fields map[string]staticType
+  methods map[string]staticType
+  getters map[string]staticType
+  statics map[string]staticType
+}
+
+var anyType = staticType{kind: typekind.ANY}
+
+var typeScopes []map[string]staticType
+var currentReturn *staticType
+var currentClassName string
+
+// Classes are known by a key unique to their declaration, so that a class
+// shadowing another of the same name gets its own entry. classScopes maps
+// the names in scope to those keys.
+var classTypes map[string]*classInfo
+var classScopes []map[string]string
+
+// narrowings records, for each scope, the nullable variables a nil check has
+// shown to hold a value there (true), or that an assignment may have set to
+// nil since (false).
+var narrowings []map[string]bool
+
+type Checked interface {
+  VisitCheck() staticType
+}
+
+func TypeCheck(statements []Stmt) {
+  typeScopes = []map[string]staticType{make(map[string]staticType)}
+  classTypes = make(map[string]*classInfo)
+  classScopes = []map[string]string{make(map[string]string)}
+  narrowings = []map[string]bool{make(map[string]bool)}
+  currentReturn = nil
+  currentClassName = ""
+
+  checkStmts(statements)
+}
+
+func classKey(name token.Token) string {
+  return fmt.Sprintf("%s@%d", name.Lexeme, name.Offset)
+}
+
+func className(key string) string {
+  at := strings.LastIndex(key, "@")
+  if at < 0 {return key}
+  return key[:at]
+}
+
+// Classes are declared at the start of the scope they appear in, so that
+// annotations can name a class declared further down. Nested classes are
+// declared alongside the class that contains them.
+func declareClasses(statements []Stmt) {
+  for _, statement := range statements {
+    class, ok := statement.(Class)
+    if ok {declareClass(class)}
+  }
+}
+
+func declareClass(class Class) string {
+  key := classKey(class.Name)
+  _, ok := classTypes[key]
+  if !ok {classTypes[key] = newClassInfo()}
+  classScopes[len(classScopes)-1][class.Name.Lexeme] = key
+  for _, nested := range class.Classes {
+    declareClass(nested)
+  }
+  return key
+}
+
+func lookupClass(name string) (string, bool) {
+  for i := len(classScopes) - 1; i >= 0; i-- {
+    key, ok := classScopes[i][name]
+    if ok {return key, true}
+  }
+  return "", false
+}
+
+func newClassInfo() *classInfo {
+  return &classInfo{"", make(map[string]staticType), make(map[string]staticType), make(map[string]staticType), make(map[string]staticType)}
+}
+
+// ancestors lists a class and its superclasses, stopping if a class repeats.
+func ancestors(class string) []string {
+  var chain []string
+  for class != "" {
+    for _, seen := range chain {
+      if seen == class {return chain}
+    }
+    info, ok := classTypes[class]
+    if !ok {break}
+    chain = append(chain, class)
+    class = info.superclass
+  }
+  return chain
+}
+
+func (t staticType) String() string {
+  var name string
+  switch t.kind {
+  case typekind.ANY:
+    return "Any"
+  case typekind.NUMBER:
+    name = "Number"
+  case typekind.STRING:
+    name = "String"
+  case typekind.BOOL:
+    name = "Bool"
+  case typekind.NIL:
+    return "Nil"
+  case typekind.LIST:
+    name = "List"
+  case typekind.TUPLE:
+    name = "Tuple"
+  case typekind.MAP:
+    name = "Map"
+  case typekind.CLASS:
+    name = "class " + className(t.class)
+  case typekind.INSTANCE:
+    name = className(t.class)
+  case typekind.FUNCTION:
+    if t.signature == nil {
+      name = "Function"
+    } else {
+      var params []string
+      for _, param := range t.signature.params {
+        params = append(params, param.String())
+      }
+      name = "fun(" + strings.Join(params, ", ") + "): " + t.signature.result.String()
+    }
+  }
+
+  if t.nullable {name += "?"}
+  return name
+}
+
+func resolveType(typeExpr TypeExpr) staticType {
+  t := resolveTypeExpr(typeExpr)
+  if t.kind != typekind.ANY {t.declared = true}
+  return t
+}
+
+func resolveTypeExpr(typeExpr TypeExpr) staticType {
+  switch e := typeExpr.(type) {
+  case NamedType:
+    switch e.Name.Lexeme {
+    case "Any":
+      return anyType
+    case "Number":
+      return staticType{kind: typekind.NUMBER}
+    case "String":
+      return staticType{kind: typekind.STRING}
+    case "Bool":
+      return staticType{kind: typekind.BOOL}
+    case "Nil", "nil":
+      return staticType{kind: typekind.NIL}
+    case "List":
+      return staticType{kind: typekind.LIST}
+    case "Tuple":
+      return staticType{kind: typekind.TUPLE}
+    case "Map":
+      return staticType{kind: typekind.MAP}
+    case "Function":
+      return staticType{kind: typekind.FUNCTION}
+    }
+
+    key, ok := lookupClass(e.Name.Lexeme)
+    if ok {
+      return staticType{kind: typekind.INSTANCE, class: key}
+    }
+    loxError.TokenError(e.Name, "Unknown type '" + e.Name.Lexeme + "'.")
+  case NullableType:
+    inner := resolveType(e.Inner)
+    if inner.kind != typekind.ANY && inner.kind != typekind.NIL {inner.nullable = true}
+    return inner
+  case CallableType:
+    sig := signature{nil, len(e.Params), false, resolveType(e.Result)}
+    for _, param := range e.Params {
+      sig.params = append(sig.params, resolveType(param))
+    }
+    return staticType{kind: typekind.FUNCTION, signature: &sig}
+  }
+  return anyType
+}
+
+func assignable(from staticType, to staticType) bool {
+  if from.kind == typekind.ANY || to.kind == typekind.ANY {return true}
+  if from.kind == typekind.NIL {return to.nullable || to.kind == typekind.NIL}
+  if from.nullable && !to.nullable {return false}
+  if from.kind != to.kind {return false}
+
+  switch from.kind {
+  case typekind.INSTANCE:
+    return isSubclass(from.class, to.class)
+  case typekind.CLASS:
+    return from.class == to.class
+  case typekind.FUNCTION:
+    if from.signature == nil || to.signature == nil {return true}
+    if len(from.signature.params) != len(to.signature.params) {return false}
+    for i := range from.signature.params {
+      if !assignable(to.signature.params[i], from.signature.params[i]) {return false}
+    }
+    return assignable(from.signature.result, to.signature.result)
+  }
+  return true
+}
+
+func isSubclass(sub string, super string) bool {
+  if sub == super {return true}
+  for _, class := range ancestors(sub) {
+    if class == super {return true}
+  }
+  return false
+}
+
+func memberType(class string, name string) (staticType, bool) {
+  for _, ancestor := range ancestors(class) {
+    info := classTypes[ancestor]
+    for _, members := range []map[string]staticType{info.fields, info.getters, info.methods} {
+      t, ok := members[name]
+      if ok {return t, true}
+    }
+  }
+  return anyType, false
+}
+
+func fieldType(class string, name string) (staticType, bool) {
+  for _, ancestor := range ancestors(class) {
+    t, ok := classTypes[ancestor].fields[name]
+    if ok {return t, true}
+  }
+  return anyType, false
+}
+
+func functionSignature(function Function) signature {
+  sig := signature{nil, 0, function.Rest != nil, resolveType(function.ReturnType)}
+  for i, param := range function.Params {
+    name, ok := param.(NamePattern)
+    if ok {
+      sig.params = append(sig.params, resolveType(name.Type))
+    } else {
+      sig.params = append(sig.params, anyType)
+    }
+    if !hasDefault(param) {sig.required = i + 1}
+  }
+  return sig
+}
+
+// Calls to functions without any annotations are left to the runtime, so
+// unannotated scripts behave exactly as they did before type checking.
+func (s signature) annotated() bool {
+  if s.result.kind != typekind.ANY {return true}
+  for _, param := range s.params {
+    if param.kind != typekind.ANY {return true}
+  }
+  return false
+}
+
+func checkFunction(function Function, sig signature, kind string) {
+  if StrictTypes && !strings.HasPrefix(function.Name.Lexeme, "_") {
+    requireAnnotations(function, kind)
+  }
+
+  enclosing := currentReturn
+  result := sig.result
+  currentReturn = &result
+  if kind == "method" && function.Name.Lexeme == "init" {currentReturn = nil}
+
+  beginTypeScope()
+  for _, param := range function.Params {
+    declarePatternTypes(param)
+  }
+  if function.Rest != nil {
+    declareType(function.Rest.Lexeme, staticType{kind: typekind.LIST})
+  }
+  checkContracts(function.Requires)
+  if len(function.Ensures) > 0 {
+    declareType("result", sig.result)
+    checkContracts(function.Ensures)
+  }
+  checkStmts(function.Body)
+  endTypeScope()
+
+  currentReturn = enclosing
+}
+
+func requireAnnotations(function Function, kind string) {
+  for _, param := range function.Params {
+    switch p := param.(type) {
+    case NamePattern:
+      if p.Type == nil {
+        loxError.TokenError(p.Name, fmt.Sprintf("Missing type annotation for parameter '%s' of public %s '%s'.", p.Name.Lexeme, kind, function.Name.Lexeme))
+      }
+    case ListPattern:
+      loxError.TokenError(p.Bracket, fmt.Sprintf("Destructured parameters of public %s '%s' can't be annotated.", kind, function.Name.Lexeme))
+    case ObjectPattern:
+      loxError.TokenError(p.Brace, fmt.Sprintf("Destructured parameters of public %s '%s' can't be annotated.", kind, function.Name.Lexeme))
+    }
+  }
+
+  if function.ReturnType == nil && !(kind == "method" && function.Name.Lexeme == "init") {
+    loxError.TokenError(function.Name, fmt.Sprintf("Missing return type annotation for public %s '%s'.", kind, function.Name.Lexeme))
+  }
+}
+
+func declarePatternTypes(pattern Pattern) {
+  switch p := pattern.(type) {
+  case NamePattern:
+    declared := resolveType(p.Type)
+    if p.Default != nil {
+      def := checkExpr(p.Default)
+      if !assignable(def, declared) {
+        loxError.TokenError(p.Name, fmt.Sprintf("Default value for '%s' must be %s, not %s.", p.Name.Lexeme, declared, def))
+      }
+    }
+    declareType(p.Name.Lexeme, declared)
+  case ListPattern:
+    if p.Default != nil {checkExpr(p.Default)}
+    for _, element := range p.Elements {
+      declarePatternTypes(element)
+    }
+    if p.Rest != nil {declareType(p.Rest.Lexeme, staticType{kind: typekind.LIST})}
+  case ObjectPattern:
+    if p.Default != nil {checkExpr(p.Default)}
+    for _, property := range p.Properties {
+      declarePatternTypes(property.Value)
+    }
+    if p.Rest != nil {declareType(p.Rest.Lexeme, anyType)}
+  }
+}
+
+func checkArguments(paren token.Token, sig signature, arguments []staticType) {
+  max := len(sig.params)
+  if len(arguments) < sig.required || (!sig.variadic && len(arguments) > max) {
+    if sig.required == max && !sig.variadic {
+      loxError.TokenError(paren, fmt.Sprintf("Expected %d arguments but got %d.", max, len(arguments)))
+    } else if len(arguments) < sig.required {
+      loxError.TokenError(paren, fmt.Sprintf("Expected at least %d arguments but got %d.", sig.required, len(arguments)))
+    } else {
+      loxError.TokenError(paren, fmt.Sprintf("Expected at most %d arguments but got %d.", max, len(arguments)))
+    }
+    return
+  }
+
+  for i, argument := range arguments {
+    if i >= len(sig.params) {break}
+    if !assignable(argument, sig.params[i]) {
+      loxError.TokenError(paren, fmt.Sprintf("Argument %d expects %s but got %s.", i + 1, sig.params[i], argument))
+    }
+  }
+}
+
+func hasSpread(arguments []Expr) bool {
+  for _, argument := range arguments {
+    _, ok := argument.(Spread)
+    if ok {return true}
+  }
+  return false
+}
+
+func checkDecorators(decorators []Decorator) {
+  for _, decorator := range decorators {
+    checkExpr(decorator.Expression)
+  }
+}
+
+func requireNumber(operator token.Token, operand staticType) {
+  if !operand.declared {return}
+  if operand.kind != typekind.NUMBER || operand.nullable {
+    loxError.TokenError(operator, fmt.Sprintf("Operand of '%s' must be a number, not %s.", operator.Lexeme, operand))
+  }
+}
+
+func (e Expression) VisitCheck() staticType {
+  checkExpr(e.Expression)
+  return anyType
+}
+
+func (e Print) VisitCheck() staticType {
+  checkExpr(e.Expression)
+  return anyType
+}
+
+func (e Block) VisitCheck() staticType {
+  beginTypeScope()
+  checkStmts(e.Statements)
+  endTypeScope()
+  return anyType
+}
+
+func (e If) VisitCheck() staticType {
+  checkExpr(e.Condition)
+  checkNarrowed(e.ThenBranch, nonNilWhen(e.Condition, true))
+  if e.ElseBranch != nil {checkNarrowed(e.ElseBranch, nonNilWhen(e.Condition, false))}
+
+  // A branch that never finishes leaves what the other branch knew.
+  if exits(e.ThenBranch) {narrow(nonNilWhen(e.Condition, false))}
+  if e.ElseBranch != nil && exits(e.ElseBranch) {narrow(nonNilWhen(e.Condition, true))}
+  return anyType
+}
+
+func checkNarrowed(statement Stmt, names []string) {
+  beginTypeScope()
+  narrow(names)
+  checkStmt(statement)
+  endTypeScope()
+}
+
+// nonNilWhen finds the variables that a condition shows aren't nil when it
+// evaluates to outcome.
+func nonNilWhen(condition Expr, outcome bool) []string {
+  switch c := condition.(type) {
+  case Grouping:
+    return nonNilWhen(c.Expression, outcome)
+  case Unary:
+    if c.Operator.TokenType == token.BANG {return nonNilWhen(c.Right, !outcome)}
+  case Binary:
+    if c.Operator.TokenType != token.EQUAL_EQUAL && c.Operator.TokenType != token.BANG_EQUAL {return nil}
+    if (c.Operator.TokenType == token.BANG_EQUAL) != outcome {return nil}
+    variable, ok := c.Left.(Variable)
+    other := c.Right
+    if !ok {
+      variable, ok = c.Right.(Variable)
+      other = c.Left
+    }
+    literal, isLiteral := other.(Literal)
+    if ok && isLiteral && literal.Value == nil {return []string{variable.Name.Lexeme}}
+  }
+  return nil
+}
+
+// exits reports whether a statement always returns or breaks.
+func exits(statement Stmt) bool {
+  switch s := statement.(type) {
+  case Return, Break:
+    return true
+  case Block:
+    for _, inner := range s.Statements {
+      if exits(inner) {return true}
+    }
+  case If:
+    return s.ElseBranch != nil && exits(s.ThenBranch) && exits(s.ElseBranch)
+  }
+  return false
+}
+
+func (e While) VisitCheck() staticType {
+  checkExpr(e.Condition)
+  checkNarrowed(e.Body, nonNilWhen(e.Condition, true))
+  if e.Else != nil {checkStmt(e.Else)}
+  return anyType
+}
+
+func (e DoWhile) VisitCheck() staticType {
+  checkStmt(e.Body)
+  checkExpr(e.Condition)
+  return anyType
+}
+
+func (e Switch) VisitCheck() staticType {
+  checkExpr(e.Subject)
+  for _, c := range e.Cases {
+    for _, value := range c.Values {
+      checkExpr(value)
+    }
+    beginTypeScope()
+    checkStmts(c.Body)
+    endTypeScope()
+  }
+  return anyType
+}
+
+func (e Assert) VisitCheck() staticType {
+  checkContracts([]Contract{Contract(e)})
+  return anyType
+}
+
+func (e Old) VisitCheck() staticType {
+  return checkExpr(e.Expression)
+}
+
+func checkContracts(clauses []Contract) {
+  for _, clause := range clauses {
+    checkExpr(clause.Condition)
+    if clause.Message != nil {checkExpr(clause.Message)}
+  }
+}
+
+func (e Defer) VisitCheck() staticType {
+  checkExpr(e.Call)
+  return anyType
+}
+
+func (e Return) VisitCheck() staticType {
+  value := staticType{kind: typekind.NIL}
+  if e.Value != nil {value = checkExpr(e.Value)}
+
+  if currentReturn != nil && !assignable(value, *currentReturn) {
+    loxError.TokenError(e.Keyword, fmt.Sprintf("Can't return %s from a function declared to return %s.", value, *currentReturn))
+  }
+  return anyType
+}
+
+func (e Var) VisitCheck() staticType {
+  initializer := staticType{kind: typekind.NIL}
+  if e.Initializer != nil {initializer = checkExpr(e.Initializer)}
+
+  name, ok := e.Pattern.(NamePattern)
+  if !ok {
+    declarePatternTypes(e.Pattern)
+    return anyType
+  }
+
+  declared := anyType
+  if name.Type != nil {
+    declared = resolveType(name.Type)
+    if e.Initializer == nil && !assignable(initializer, declared) {
+      loxError.TokenError(name.Name, fmt.Sprintf("Variable '%s' of type %s must be initialised.", name.Name.Lexeme, declared))
+    } else if !assignable(initializer, declared) {
+      loxError.TokenError(name.Name, fmt.Sprintf("Can't initialise '%s' of type %s with %s.", name.Name.Lexeme, declared, initializer))
+    }
+  } else if e.Constant {
+    declared = initializer
+  }
+
+  declareType(name.Name.Lexeme, declared)
+  return anyType
+}
+
+func (e Function) VisitCheck() staticType {
+  checkDecorators(e.Decorators)
+  sig := functionSignature(e)
+  function := staticType{kind: typekind.FUNCTION, signature: &sig}
+  if len(e.Decorators) > 0 {function = anyType}
+  declareType(e.Name.Lexeme, function)
+
+  checkFunction(e, sig, "function")
+  return function
+}
+
+func (e Class) VisitCheck() staticType {
+  class := staticType{kind: typekind.CLASS, class: declareClass(e)}
+  if len(e.Decorators) > 0 {class = anyType}
+  declareType(e.Name.Lexeme, class)
+  checkClass(e)
+  return class
+}
+
+// Anonymous classes can't be named in annotations, so nothing is known about
+// them.
+func (e ClassExpr) VisitCheck() staticType {
+  if e.Declaration.Name.TokenType != token.IDENTIFIER {
+    checkClass(e.Declaration)
+    return anyType
+  }
+  class := staticType{kind: typekind.CLASS, class: declareClass(e.Declaration)}
+  checkClass(e.Declaration)
+  if len(e.Declaration.Decorators) > 0 {return anyType}
+  return class
+}
+
+func checkClass(e Class) {
+  key := classKey(e.Name)
+  info, ok := classTypes[key]
+  if !ok {
+    info = newClassInfo()
+    classTypes[key] = info
+  }
+  if e.Superclass != nil {
+    checkExpr(*e.Superclass)
+    info.superclass, _ = lookupClass(e.Superclass.Name.Lexeme)
+  }
+
+  checkDecorators(e.Decorators)
+
+  for _, field := range e.Fields {
+    info.fields[field.Name.Lexeme] = resolveType(field.Type)
+  }
+
+  signatures := make(map[string]signature)
+  for _, method := range e.Methods {
+    sig := functionSignature(method)
+    signatures[method.Name.Lexeme] = sig
+    info.methods[method.Name.Lexeme] = staticType{kind: typekind.FUNCTION, signature: &sig}
+    if len(method.Decorators) > 0 {info.methods[method.Name.Lexeme] = anyType}
+  }
+  for _, getter := range e.Getters {
+    info.getters[getter.Name.Lexeme] = resolveType(getter.ReturnType)
+  }
+  for _, method := range e.StaticMethods {
+    sig := functionSignature(method)
+    info.statics[method.Name.Lexeme] = staticType{kind: typekind.FUNCTION, signature: &sig}
+    if len(method.Decorators) > 0 {info.statics[method.Name.Lexeme] = anyType}
+  }
+  for _, nested := range e.Classes {
+    info.statics[nested.Name.Lexeme] = staticType{kind: typekind.CLASS, class: classKey(nested.Name)}
+    if len(nested.Decorators) > 0 {info.statics[nested.Name.Lexeme] = anyType}
+    checkClass(nested)
+  }
+
+  enclosingClass := currentClassName
+  currentClassName = key
+
+  for _, method := range e.Methods {
+    checkDecorators(method.Decorators)
+    checkFunction(method, signatures[method.Name.Lexeme], "method")
+  }
+  for _, getter := range e.Getters {
+    checkFunction(getter, functionSignature(getter), "getter")
+  }
+  for _, method := range e.StaticMethods {
+    checkDecorators(method.Decorators)
+    checkFunction(method, functionSignature(method), "method")
+  }
+
+  currentClassName = enclosingClass
+}
+
+func (e Literal) VisitCheck() staticType {
+  switch e.Value.(type) {
+  case float64:
+    return staticType{kind: typekind.NUMBER}
+  case string:
+    return staticType{kind: typekind.STRING}
+  case bool:
+    return staticType{kind: typekind.BOOL}
+  case nil:
+    return staticType{kind: typekind.NIL}
+  }
+  return anyType
+}
+
+func (e Variable) VisitCheck() staticType {
+  return lookupType(e.Name.Lexeme)
+}
+
+func (e Assign) VisitCheck() staticType {
+  value := checkExpr(e.Value)
+  target := declaredType(e.Name.Lexeme)
+  if !assignable(value, target) {
+    loxError.TokenError(e.Name, fmt.Sprintf("Can't assign %s to '%s' of type %s.", value, e.Name.Lexeme, target))
+  }
+  noteAssignment(e.Name.Lexeme, value)
+  return value
+}
+
+func (e Destructure) VisitCheck() staticType {
+  return checkExpr(e.Value)
+}
+
+func (e Grouping) VisitCheck() staticType {
+  return checkExpr(e.Expression)
+}
+
+func (e Unary) VisitCheck() staticType {
+  right := checkExpr(e.Right)
+  if e.Operator.TokenType == token.MINUS {
+    requireNumber(e.Operator, right)
+    return staticType{kind: typekind.NUMBER, declared: right.declared}
+  }
+  return staticType{kind: typekind.BOOL}
+}
+
+func (e Binary) VisitCheck() staticType {
+  left := checkExpr(e.Left)
+  right := checkExpr(e.Right)
+
+  switch e.Operator.TokenType {
+  case token.MINUS, token.SLASH, token.STAR:
+    requireNumber(e.Operator, left)
+    requireNumber(e.Operator, right)
+    return staticType{kind: typekind.NUMBER, declared: left.declared && right.declared}
+  case token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL:
+    requireNumber(e.Operator, left)
+    requireNumber(e.Operator, right)
+    return staticType{kind: typekind.BOOL}
+  case token.EQUAL_EQUAL, token.BANG_EQUAL:
+    return staticType{kind: typekind.BOOL}
+  case token.IS:
+    if right.declared && right.kind != typekind.CLASS {
+      loxError.TokenError(e.Operator, fmt.Sprintf("Right operand of 'is' must be a class, not %s.", right))
+    }
+    return staticType{kind: typekind.BOOL}
+  case token.PLUS:
+    if left.kind == typekind.ANY || right.kind == typekind.ANY {return anyType}
+    declared := left.declared && right.declared
+    if left.kind == typekind.NUMBER && right.kind == typekind.NUMBER && !left.nullable && !right.nullable {
+      return staticType{kind: typekind.NUMBER, declared: declared}
+    }
+    if (left.kind == typekind.STRING && !left.nullable) || (right.kind == typekind.STRING && !right.nullable) {
+      return staticType{kind: typekind.STRING, declared: declared}
+    }
+    if !left.declared && !right.declared {return anyType}
+    loxError.TokenError(e.Operator, fmt.Sprintf("Operands of '+' must be two numbers or two strings, not %s and %s.", left, right))
+  }
+  return anyType
+}
+
+func (e Logical) VisitCheck() staticType {
+  left := checkExpr(e.Left)
+  right := checkExpr(e.Right)
+  if left.String() == right.String() {return left}
+  return anyType
+}
+
+func (e Call) VisitCheck() staticType {
+  callee := checkExpr(e.Callee)
+
+  var arguments []staticType
+  for _, argument := range e.Arguments {
+    arguments = append(arguments, checkExpr(argument))
+  }
+  for _, named := range e.Named {
+    checkExpr(named.Value)
+  }
+
+  var sig *signature
+  result := anyType
+  switch callee.kind {
+  case typekind.ANY:
+  case typekind.FUNCTION:
+    sig = callee.signature
+    if sig != nil {result = sig.result}
+  case typekind.CLASS:
+    result = staticType{kind: typekind.INSTANCE, class: callee.class}
+    init, ok := memberType(callee.class, "init")
+    if ok {sig = init.signature}
+  default:
+    if callee.declared {
+      loxError.TokenError(e.Paren, fmt.Sprintf("Can only call functions and classes, not %s.", callee))
+    }
+  }
+
+  if callee.nullable {
+    loxError.TokenError(e.Paren, fmt.Sprintf("Can't call a value of type %s that may be nil.", callee))
+  }
+  if sig != nil && sig.annotated() && len(e.Named) == 0 && !hasSpread(e.Arguments) {
+    checkArguments(e.Paren, *sig, arguments)
+  }
+  return result
+}
+
+func (e Get) VisitCheck() staticType {
+  object := checkExpr(e.Object)
+  switch object.kind {
+  case typekind.INSTANCE:
+    member, _ := memberType(object.class, e.Name.Lexeme)
+    return member
+  case typekind.CLASS:
+    info, ok := classTypes[object.class]
+    if ok {
+      member, ok := info.statics[e.Name.Lexeme]
+      if ok {return member}
+    }
+  }
+  return anyType
+}
+
+func (e Set) VisitCheck() staticType {
+  object := checkExpr(e.Object)
+  value := checkExpr(e.Value)
+
+  if object.kind == typekind.INSTANCE {
+    field, ok := fieldType(object.class, e.Name.Lexeme)
+    if ok && !assignable(value, field) {
+      loxError.TokenError(e.Name, fmt.Sprintf("Can't assign %s to field '%s' of type %s.", value, e.Name.Lexeme, field))
+    }
+  }
+  return value
+}
+
+func (e This) VisitCheck() staticType {
+  if currentClassName == "" {return anyType}
+  return staticType{kind: typekind.INSTANCE, class: currentClassName}
+}
+
+func (e List) VisitCheck() staticType {
+  for _, element := range e.Elements {
+    checkExpr(element)
+  }
+  return staticType{kind: typekind.LIST}
+}
+
+func (e Spread) VisitCheck() staticType {
+  return checkExpr(e.Expression)
+}
+
+func (e Tuple) VisitCheck() staticType {
+  for _, element := range e.Elements {
+    checkExpr(element)
+  }
+  return staticType{kind: typekind.TUPLE}
+}
+
+func (e Index) VisitCheck() staticType {
+  checkIndex(e.Bracket, checkExpr(e.Object), checkExpr(e.Index))
+  return anyType
+}
+
+func (e SetIndex) VisitCheck() staticType {
+  checkIndex(e.Bracket, checkExpr(e.Object), checkExpr(e.Index))
+  return checkExpr(e.Value)
+}
+
+// Maps take keys of any type, so only list and tuple indices must be numbers.
+func checkIndex(bracket token.Token, object staticType, index staticType) {
+  if object.kind == typekind.LIST || object.kind == typekind.TUPLE {
+    requireNumber(bracket, index)
+  }
+}
+
+func checkStmts(statements []Stmt) {
+  declareClasses(statements)
+  for _, statement := range statements {
+    checkStmt(statement)
+  }
+}
+
+func checkStmt(statement Stmt) {
+  c, ok := statement.(Checked)
+  if ok {c.VisitCheck()}
+}
+
+func checkExpr(expr Expr) staticType {
+  c, ok := expr.(Checked)
+  if !ok {return anyType}
+  return c.VisitCheck()
+}
+
+func beginTypeScope() {
+  typeScopes = append(typeScopes, make(map[string]staticType))
+  classScopes = append(classScopes, make(map[string]string))
+  narrowings = append(narrowings, make(map[string]bool))
+}
+
+func endTypeScope() {
+  typeScopes = typeScopes[:len(typeScopes)-1]
+  classScopes = classScopes[:len(classScopes)-1]
+  narrowings = narrowings[:len(narrowings)-1]
+}
+
+func declareType(name string, t staticType) {
+  typeScopes[len(typeScopes)-1][name] = t
+  delete(narrowings[len(narrowings)-1], name)
+}
+
+// lookupType gives the type of a variable where it is read, which a nil
+// check may have narrowed from its declared type.
+func lookupType(name string) staticType {
+  for i := len(typeScopes) - 1; i >= 0; i-- {
+    t, ok := typeScopes[i][name]
+    if !ok {continue}
+    if t.nullable && narrowed(name, i) {t.nullable = false}
+    return t
+  }
+  return anyType
+}
+
+func declaredType(name string) staticType {
+  for i := len(typeScopes) - 1; i >= 0; i-- {
+    t, ok := typeScopes[i][name]
+    if ok {return t}
+  }
+  return anyType
+}
+
+func narrowed(name string, scope int) bool {
+  for i := len(narrowings) - 1; i >= scope; i-- {
+    known, ok := narrowings[i][name]
+    if ok {return known}
+  }
+  return false
+}
+
+func narrow(names []string) {
+  for _, name := range names {
+    narrowings[len(narrowings)-1][name] = true
+  }
+}
+
+// An assignment that may store nil undoes narrowing out to the variable's
+// own scope, since it may have happened in any branch.
+func noteAssignment(name string, value staticType) {
+  if value.kind != typekind.NIL && value.kind != typekind.ANY && !value.nullable {
+    narrow([]string{name})
+    return
+  }
+  for i := len(typeScopes) - 1; i >= 0; i-- {
+    narrowings[i][name] = false
+    _, ok := typeScopes[i][name]
+    if ok {return}
+  }
+}
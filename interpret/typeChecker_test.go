@@ -0,0 +1,68 @@
+package interpret_test
+
+import (
+	"testing"
+
+	"lox/interpret"
+)
+
+func TestNilNarrowing(t *testing.T) {
+  runAll(t, []expectation{
+    {"early return", "fun f(n: Number?): Number { if (n == nil) return 0; return n + 1; } print f(nil); print f(2);", "0\n3\n", ""},
+    {"guarded branch", "fun f(n: Number?): Number { if (n != nil) { return n * 2; } return 0; } print f(3);", "6\n", ""},
+    {"nil on the right", "fun f(n: Number?): Number { if (nil == n) return 0; return n - 1; } print f(3);", "2\n", ""},
+    {"else branch", "fun f(n: Number?): Number { if (n == nil) { return 0; } else { return n - 1; } } print f(4);", "3\n", ""},
+    {"negated", "fun f(n: Number?): Number { if (!(n != nil)) return 0; return -n; } print f(4);", "-4\n", ""},
+    {"while", "fun f(n: Number?) { while (n != nil) { print n + 1; n = nil; } } f(1);", "2\n", ""},
+    {"break", "fun f(n: Number?) { while (true) { if (n == nil) break; print n + 1; return; } } f(1);", "2\n", ""},
+    {"unguarded", "fun f(n: Number?): Number { return n + 1; }", "", "not Number? and Number"},
+    {"guard in the wrong branch", "fun f(n: Number?) { if (n == nil) { print n + 1; } }", "", "not Number? and Number"},
+    {"branch that falls through", "fun f(n: Number?): Number { if (n == nil) print 0; return n + 1; }", "", "not Number? and Number"},
+    {"reassigned nil", "fun f(n: Number?, c: Bool): Number { if (n == nil) return 0; if (c) n = nil; return n + 1; }", "", "not Number? and Number"},
+    {"narrowing ends with its scope", "fun f(n: Number?): Number { if (n != nil) { print n + 1; } return n + 1; }", "", "not Number? and Number"},
+    {"assignment to nullable still allowed", "fun f(n: Number?) { if (n == nil) return; n = nil; print n; } f(1);", "nil\n", ""},
+  })
+}
+
+func TestTypeChecker(t *testing.T) {
+  runAll(t, []expectation{
+    {"annotated program runs", `var tcA: Number = 1; fun tcAdd(a: Number, b: Number): Number { return a + b; } print tcAdd(tcA, 2);`, "3\n", ""},
+    {"unannotated code is unchecked", `fun tcLoose(a) { return a + 1; } print tcLoose(1);`, "2\n", ""},
+    {"Any accepts anything", `var tcAny: Any = "text"; tcAny = 1; print tcAny;`, "1\n", ""},
+    {"nullable accepts nil", `var tcMaybe: String? = nil; print tcMaybe;`, "nil\n", ""},
+    {"unknown type", `var tcB: Numbr = 1;`, "", "Unknown type 'Numbr'."},
+    {"bad initialiser", `var tcC: Number = "one";`, "", "Can't initialise 'tcC' of type Number with String."},
+    {"missing initialiser", `var tcD: Number;`, "", "Variable 'tcD' of type Number must be initialised."},
+    {"bad assignment", `var tcE: Number = 1; tcE = "two";`, "", "Can't assign String to 'tcE' of type Number."},
+    {"nil into non-nullable", `var tcF: String = nil;`, "", "Can't initialise 'tcF' of type String with Nil."},
+    {"bad default", `fun tcG(n: Number = "x") {}`, "", "Default value for 'n' must be Number, not String."},
+    {"bad return", `fun tcH(): Number { return "x"; }`, "", "Can't return String from a function declared to return Number."},
+    {"bad argument", `fun tcI(n: Number) {} tcI("x");`, "", "Argument 1 expects Number but got String."},
+    {"too few arguments", `fun tcJ(a: Number, b: Number) {} tcJ(1);`, "", "Expected 2 arguments but got 1."},
+    {"too many arguments", `fun tcK(a: Number, b: Number = 2) {} tcK(1, 2, 3);`, "", "Expected at most 2 arguments but got 3."},
+    {"not enough with defaults", `fun tcL(a: Number, b: Number, c: Number = 2) {} tcL(1);`, "", "Expected at least 2 arguments but got 1."},
+    {"number and string concatenate", `var tcM: Number = 1; var tcN: String = "s"; print tcM + tcN;`, "1s\n", ""},
+    {"bad plus", `var tcM2: Number = 1; var tcN2: Bool = true; print tcM2 + tcN2;`, "", "Operands of '+' must be two numbers or two strings, not Number and Bool."},
+    {"bad negation", `var tcO: String = "s"; print -tcO;`, "", "Operand of '-' must be a number, not String."},
+    {"calling a number", `var tcP: Number = 1; tcP();`, "", "Can only call functions and classes, not Number."},
+    {"calling a nullable function", `var tcQ: Function? = nil; tcQ();`, "", "Can't call a value of type Function? that may be nil."},
+    {"field assignment", `class TcPoint { x: Number; init() { this.x = 0; } } var tcR: TcPoint = TcPoint(); tcR.x = 1; print tcR.x;`, "1\n", ""},
+    {"bad field assignment", `class TcPair { x: Number; init() { this.x = 0; } } var tcR2: TcPair = TcPair(); tcR2.x = "one";`, "", "Can't assign String to field 'x' of type Number."},
+    {"class type mismatch", `class TcCat {} class TcDog {} var tcS: TcCat = TcDog();`, "", "Can't initialise 'tcS' of type TcCat with TcDog."},
+    {"subclass is assignable", `class TcAnimal {} class TcBird < TcAnimal {} var tcT: TcAnimal = TcBird(); print "ok";`, "ok\n", ""},
+    {"is needs a class", `var tcU: Number = 2; print 1 is tcU;`, "", "Right operand of 'is' must be a class, not Number."},
+  })
+}
+
+func TestStrictTypes(t *testing.T) {
+  interpret.StrictTypes = true
+  defer func() {interpret.StrictTypes = false}()
+  runAll(t, []expectation{
+    {"fully annotated", `fun stA(n: Number): Number { return n; } print stA(1);`, "1\n", ""},
+    {"private functions are exempt", `fun _stB(n) { return n; } print _stB(2);`, "2\n", ""},
+    {"missing parameter type", `fun stC(n): Number { return n; }`, "", "Missing type annotation for parameter 'n' of public function 'stC'."},
+    {"missing return type", `fun stD(n: Number) { return n; }`, "", "Missing return type annotation for public function 'stD'."},
+    {"destructured parameter", `fun stE([a, b]): Number { return 1; }`, "", "Destructured parameters of public function 'stE' can't be annotated."},
+    {"initialiser needs no return type", `class StF { init(n: Number) { this.n = n; } } print StF(3).n;`, "3\n", ""},
+  })
+}
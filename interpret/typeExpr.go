@@ -0,0 +1,44 @@
+package interpret
+
+import (
+	"lox/token"
+	"strings"
+)
+
+type TypeExpr interface {
+  AstPrint() string
+}
+
+type NamedType struct {
+  Name token.Token
+}
+
+type NullableType struct {
+  Inner TypeExpr
+  Question token.Token
+}
+
+type CallableType struct {
+  Keyword token.Token
+  Params []TypeExpr
+  Result TypeExpr
+}
+
+func (e NamedType) AstPrint() string {
+  return e.Name.Lexeme
+}
+
+func (e NullableType) AstPrint() string {
+  return e.Inner.AstPrint() + "?"
+}
+
+func (e CallableType) AstPrint() string {
+  var params []string
+  for _, param := range e.Params {
+    params = append(params, param.AstPrint())
+  }
+
+  out := "fun(" + strings.Join(params, ", ") + ")"
+  if e.Result != nil {out += ": " + e.Result.AstPrint()}
+  return out
+}
@@ -5,6 +5,8 @@ import (
 	"lox/environment"
 	"lox/loxError"
 	"lox/token"
+	"reflect"
+	"strings"
 )
 
 func (e Literal) VisitExpr(_ environment.Environment) (any, error) {
@@ -38,25 +40,25 @@ func (e Set) VisitExpr(env environment.Environment) (any, error) {
   if err != nil {return value, err}
 
   if ok {
-    inst.Set(e.Name, value)
-  } else {
-    class.Set(e.Name, value)
+    return value, inst.Set(e.Name, value)
   }
-  return value, nil
+  return value, class.Set(e.Name, value)
 }
 
 func (e Super) VisitExpr(env environment.Environment) (any, error) {
   distance := locals[e]
   superclass, _ := environment.GetAt(&env, distance, "super").(LoxClass)
 
-  object, _ := environment.GetAt(&env, distance - 1, "this.").(LoxInstance)
+  object, _ := environment.GetAt(&env, distance - 1, "this").(LoxInstance)
 
   method, err := superclass.FindMethod(e.Method.Lexeme)
   if err != nil {
-    return method, loxError.RuntimeError{e.Method, "Undefined property '" + e.Method.Lexeme + "'."}
+    return nil, loxError.RuntimeError{e.Method, "Undefined property '" + e.Method.Lexeme + "'."}
   }
   
-  return method.Bind(object), nil
+  bound, err := bindMethod(method, object)
+  if err != nil {return nil, err}
+  return bound, nil
 }
 
 func (e This) VisitExpr(env environment.Environment) (any, error) {
@@ -65,24 +67,162 @@ func (e This) VisitExpr(env environment.Environment) (any, error) {
 }
 
 func (e Call) VisitExpr(env environment.Environment) (any, error) {
+  callee, arguments, err := e.evaluateOperands(env)
+  if err != nil {return nil, err}
+  return e.apply(env, callee, arguments)
+}
+
+func (e Call) evaluateOperands(env environment.Environment) (any, []any, error) {
   callee, err := evaluate(e.Callee, env)
-  if err != nil {return callee, err}
+  if err != nil {return nil, nil, err}
 
   var arguments []any
   for _, argument := range e.Arguments {
     toAdd, err := evaluate(argument, env)
-    if err != nil {return toAdd, err}
-    arguments = append(arguments, toAdd)
+    if err != nil {return nil, nil, err}
+
+    spread, ok := argument.(Spread)
+    if ok {
+      list, ok := toAdd.(*LoxList)
+      if !ok {
+        return nil, nil, loxError.RuntimeError{spread.Ellipsis, "Can only spread lists."}
+      }
+      arguments = append(arguments, list.Elements...)
+    } else {
+      arguments = append(arguments, toAdd)
+    }
   }
 
+  if len(e.Named) == 0 {return callee, arguments, nil}
+
   function, ok := callee.(LoxCallable)
   if !ok {
-    return nil, loxError.RuntimeError{e.Paren, "Can only call functions and classes."}
+    return nil, nil, loxError.RuntimeError{e.Paren, "Can only call functions and classes."}
+  }
+  arguments, err = bindNamedArguments(e, env, function, arguments)
+  return callee, arguments, err
+}
+
+// Named arguments have already been matched and checked against the callee's
+// parameters by evaluateOperands.
+func (e Call) apply(env environment.Environment, callee any, arguments []any) (any, error) {
+  if len(e.Named) == 0 {
+    return callValue(env, e.Paren, callee, arguments)
+  }
+  return invoke(env, e.Paren, callee.(LoxCallable), arguments)
+}
+
+func callValue(env environment.Environment, paren token.Token, callee any, arguments []any) (any, error) {
+  function, ok := callee.(LoxCallable)
+  if !ok {
+    return nil, loxError.RuntimeError{paren, "Can only call functions and classes."}
+  }
+
+  err := checkArity(paren, function, len(arguments))
+  if err != nil {return nil, err}
+  return invoke(env, paren, function, arguments)
+}
+
+func invoke(env environment.Environment, paren token.Token, function LoxCallable, arguments []any) (any, error) {
+  value, err := function.Call(env, arguments)
+  // A class passes on whatever its initializer returned, which for a native
+  // class is a plain error.
+  _, native := function.(ProtoLoxCallable)
+  _, class := function.(LoxClass)
+  _, runtime := err.(loxError.RuntimeError)
+  _, exiting := err.(ExitError)
+  if (native || class) && err != nil && !runtime && !exiting {
+    err = loxError.RuntimeError{paren, err.Error()}
+  }
+  return value, err
+}
+
+func checkArity(paren token.Token, function LoxCallable, count int) error {
+  min, max := function.Arity()
+  if count >= min && (max < 0 || count <= max) {return nil}
+
+  var names []string
+  p, ok := function.(parameterized)
+  if ok {names = p.parameterNames()}
+
+  if count < min {
+    if len(names) >= min && names[count] != "" {
+      return loxError.RuntimeError{paren, fmt.Sprintf("Missing %s for %s %s.", plural(min - count, "argument"), plural(min - count, "parameter"), quoteNames(names[count:min]))}
+    }
+    if min == max {
+      return loxError.RuntimeError{paren, fmt.Sprintf("Expected %d arguments but got %d", min, count)}
+    }
+    return loxError.RuntimeError{paren, fmt.Sprintf("Expected at least %d arguments but got %d", min, count)}
+  }
+
+  if min == max {
+    return loxError.RuntimeError{paren, fmt.Sprintf("Expected %d arguments but got %d", max, count)}
+  }
+  return loxError.RuntimeError{paren, fmt.Sprintf("Expected at most %d arguments but got %d", max, count)}
+}
+
+func bindNamedArguments(e Call, env environment.Environment, function LoxCallable, positional []any) ([]any, error) {
+  p, ok := function.(parameterized)
+  if !ok {
+    return nil, loxError.RuntimeError{e.Paren, "Only Lox functions and classes accept named arguments."}
+  }
+  names := p.parameterNames()
+
+  min, max := function.Arity()
+  if max >= 0 && len(positional) > max {
+    return nil, checkArity(e.Paren, function, len(positional))
   }
-  if len(arguments) != function.Arity() {
-    return nil, loxError.RuntimeError{e.Paren, fmt.Sprintf("Expected %d arguments but got %d", function.Arity(), len(arguments))}
+
+  arguments := append([]any{}, positional...)
+  given := make([]bool, len(names))
+  for i := range positional {
+    if i < len(given) {given[i] = true}
   }
-  return function.Call(env, arguments)
+
+  for _, named := range e.Named {
+    index := -1
+    for i, name := range names {
+      if name == named.Name.Lexeme {index = i}
+    }
+    if index < 0 {
+      return nil, loxError.RuntimeError{named.Name, fmt.Sprintf("Unexpected argument '%s'.", named.Name.Lexeme)}
+    }
+    if given[index] {
+      return nil, loxError.RuntimeError{named.Name, fmt.Sprintf("Argument '%s' given more than once.", named.Name.Lexeme)}
+    }
+
+    value, err := evaluate(named.Value, env)
+    if err != nil {return nil, err}
+
+    for len(arguments) <= index {
+      arguments = append(arguments, nil)
+    }
+    arguments[index] = value
+    given[index] = true
+  }
+
+  var missing []string
+  for i := 0; i < min; i++ {
+    if !given[i] {missing = append(missing, names[i])}
+  }
+  if len(missing) > 0 {
+    return nil, loxError.RuntimeError{e.Paren, fmt.Sprintf("Missing %s for %s %s.", plural(len(missing), "argument"), plural(len(missing), "parameter"), quoteNames(missing))}
+  }
+
+  return arguments, nil
+}
+
+func quoteNames(names []string) string {
+  quoted := make([]string, len(names))
+  for i, name := range names {
+    quoted[i] = "'" + name + "'"
+  }
+  return strings.Join(quoted, ", ")
+}
+
+func plural(count int, word string) string {
+  if count == 1 {return word}
+  return word + "s"
 }
 
 func (e Get) VisitExpr(env environment.Environment) (any, error) {
@@ -97,7 +237,7 @@ func (e Get) VisitExpr(env environment.Environment) (any, error) {
     return class.Get(e.Name)
   }
 
-  return nil, loxError.RuntimeError{e.Name, "Only instances have properties."}
+  return getPrimitive(object, e.Name)
 }
 
 func (e Grouping) VisitExpr(env environment.Environment) (any, error) {
@@ -152,8 +292,11 @@ func (e Binary) VisitExpr(env environment.Environment) (any, error) {
     return !isEqual(left, right), nil
   case token.EQUAL_EQUAL:
     return isEqual(left, right), nil
+  case token.IS:
+    class, ok := right.(LoxClass)
+    if !ok {return nil, loxError.RuntimeError{e.Operator, "Right operand of 'is' must be a class."}}
+    return isInstance(left, class), nil
 
-    
   case token.MINUS:
     fL, fR, err := checkNumberOperands(e.Operator, left, right)
     if err != nil {return nil, err}
@@ -208,13 +351,117 @@ func (e Assign) VisitExpr(env environment.Environment) (any, error) {
   value, err := evaluate(e.Value, env)
   if err != nil {return nil, err}
 
-  distance, ok := locals[e]
+  return value, assignVariable(env, e.Name, value)
+}
+
+func (e Destructure) VisitExpr(env environment.Environment) (any, error) {
+  value, err := evaluate(e.Value, env)
+  if err != nil {return nil, err}
+
+  return value, e.Pattern.VisitPattern(env, value, func(name token.Token, value any) error {
+    return assignVariable(env, name, value)
+  })
+}
+
+// Assignment targets are resolved under the Variable they were parsed from,
+// since the Assign node itself may hold an unhashable value expression.
+func assignVariable(env environment.Environment, name token.Token, value any) error {
+  distance, ok := locals[Variable{name}]
   if ok {
-    environment.AssignAt(&env, distance, e.Name, value)
-  } else {
-    environment.Assign(&GlobalEnv, e.Name, value)
+    environment.AssignAt(&env, distance, name, value)
+    return nil
   }
+  return environment.Assign(&GlobalEnv, name, value)
+}
+
+func (e List) VisitExpr(env environment.Environment) (any, error) {
+  list := &LoxList{}
+  for _, element := range e.Elements {
+    value, err := evaluate(element, env)
+    if err != nil {return nil, err}
 
+    spread, ok := element.(Spread)
+    if ok {
+      inner, ok := value.(*LoxList)
+      if !ok {
+        return nil, loxError.RuntimeError{spread.Ellipsis, "Can only spread lists."}
+      }
+      list.Elements = append(list.Elements, inner.Elements...)
+    } else {
+      list.Elements = append(list.Elements, value)
+    }
+  }
+  return list, nil
+}
+
+func (e Tuple) VisitExpr(env environment.Environment) (any, error) {
+  elements := make([]any, len(e.Elements))
+  for i, element := range e.Elements {
+    value, err := evaluate(element, env)
+    if err != nil {return nil, err}
+    elements[i] = value
+  }
+  return LoxTuple{elements}, nil
+}
+
+func (e Spread) VisitExpr(env environment.Environment) (any, error) {
+  return evaluate(e.Expression, env)
+}
+
+func (e Index) VisitExpr(env environment.Environment) (any, error) {
+  object, err := evaluate(e.Object, env)
+  if err != nil {return nil, err}
+  index, err := evaluate(e.Index, env)
+  if err != nil {return nil, err}
+
+  var elements []any
+  switch o := object.(type) {
+  case *LoxList:
+    elements = o.Elements
+  case LoxTuple:
+    elements = o.Elements
+  case *LoxMap:
+    value, _, err := o.Get(index)
+    if err != nil {return nil, loxError.RuntimeError{e.Bracket, err.Error()}}
+    return value, nil
+  default:
+    return nil, loxError.RuntimeError{e.Bracket, "Only lists, tuples and maps can be indexed."}
+  }
+
+  i, err := elementIndex(e.Bracket, elements, index)
+  if err != nil {return nil, err}
+  return elements[i], nil
+}
+
+func (e SetIndex) VisitExpr(env environment.Environment) (any, error) {
+  object, err := evaluate(e.Object, env)
+  if err != nil {return nil, err}
+  index, err := evaluate(e.Index, env)
+  if err != nil {return nil, err}
+
+  _, tok := object.(LoxTuple)
+  if tok {
+    return nil, loxError.RuntimeError{e.Bracket, "Tuples are immutable."}
+  }
+  m, ok := object.(*LoxMap)
+  if ok {
+    value, err := evaluate(e.Value, env)
+    if err != nil {return nil, err}
+    err = m.Set(index, value)
+    if err != nil {return nil, loxError.RuntimeError{e.Bracket, err.Error()}}
+    return value, nil
+  }
+  list, ok := object.(*LoxList)
+  if !ok {
+    return nil, loxError.RuntimeError{e.Bracket, "Only lists and maps can be assigned by index."}
+  }
+
+  i, err := elementIndex(e.Bracket, list.Elements, index)
+  if err != nil {return nil, err}
+
+  value, err := evaluate(e.Value, env)
+  if err != nil {return nil, err}
+  list.Elements[i] = value
   return value, nil
 }
 
@@ -234,9 +481,61 @@ func isEqual(a any, b any) bool {
   if a == nil && b == nil {return true}
   if a == nil {return false}
 
+  tA, ok := a.(LoxTuple)
+  if ok {
+    tB, ok := b.(LoxTuple)
+    return ok && tA.equals(tB)
+  }
+  dA, ok := a.(loxDate)
+  if ok {
+    dB, ok := b.(loxDate)
+    return ok && dA.Equal(dB.Time)
+  }
+
+  kA, okA := hashKey(a)
+  kB, okB := hashKey(b)
+  if okA && okB {return kA == kB}
+
+  if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {return false}
   return a == b
 }
 
+type identityKey struct {
+  kind string
+  pointer uintptr
+}
+
+type dateKey struct {
+  seconds int64
+  nanoseconds int
+}
+
+// hashKey maps a value onto a comparable Go value such that two Lox values
+// are equal exactly when their keys are. Lists are mutable and unhashable.
+func hashKey(value any) (any, bool) {
+  switch v := value.(type) {
+  case nil, bool, float64, string:
+    return v, true
+  case LoxTuple:
+    builder := strings.Builder{}
+    builder.WriteString("(")
+    for _, element := range v.Elements {
+      key, ok := hashKey(element)
+      if !ok {return nil, false}
+      builder.WriteString(fmt.Sprintf("%T:%#v,", key, key))
+    }
+    builder.WriteString(")")
+    return identityKey{builder.String(), 0}, true
+  case LoxInstance:
+    return identityKey{"instance", reflect.ValueOf(v.Fields).Pointer()}, true
+  case LoxClass:
+    return identityKey{"class", reflect.ValueOf(v.Fields).Pointer()}, true
+  case loxDate:
+    return dateKey{v.Unix(), v.Nanosecond()}, true
+  }
+  return nil, false
+}
+
 func checkNumberOperand(operator token.Token, right any) (float64, error) {
   f, ok := right.(float64)
   if ok {
@@ -0,0 +1,88 @@
+package interpret
+
+import (
+	"lox/environment"
+	"lox/loxError"
+)
+
+func (e NamePattern) VisitPattern(env environment.Environment, value any, bind binder) error {
+  value, err := patternDefault(env, e.Default, value)
+  if err != nil {return err}
+
+  return bind(e.Name, value)
+}
+
+func (e ListPattern) VisitPattern(env environment.Environment, value any, bind binder) error {
+  value, err := patternDefault(env, e.Default, value)
+  if err != nil {return err}
+
+  var elements []any
+  switch v := value.(type) {
+  case *LoxList:
+    elements = v.Elements
+  case LoxTuple:
+    elements = v.Elements
+  default:
+    return loxError.RuntimeError{e.Bracket, "Can only destructure lists and tuples."}
+  }
+
+  for i, element := range e.Elements {
+    var item any
+    if i < len(elements) {item = elements[i]}
+
+    err = element.VisitPattern(env, item, bind)
+    if err != nil {return err}
+  }
+
+  if e.Rest != nil {
+    rest := &LoxList{}
+    if len(elements) > len(e.Elements) {
+      rest.Elements = append(rest.Elements, elements[len(e.Elements):]...)
+    }
+    return bind(*e.Rest, rest)
+  }
+
+  return nil
+}
+
+func (e ObjectPattern) VisitPattern(env environment.Environment, value any, bind binder) error {
+  value, err := patternDefault(env, e.Default, value)
+  if err != nil {return err}
+
+  inst, ok := value.(LoxInstance)
+  class, cok := value.(LoxClass)
+  if cok {
+    inst = class.LoxInstance
+  } else if !ok {
+    return loxError.RuntimeError{e.Brace, "Can only destructure instances."}
+  }
+
+  taken := make(map[string]bool)
+  for _, property := range e.Properties {
+    taken[property.Key.Lexeme] = true
+
+    var item any
+    if inst.Has(property.Key.Lexeme) {
+      item, err = inst.Get(property.Key)
+      if err != nil {return err}
+    }
+
+    err = property.Value.VisitPattern(env, item, bind)
+    if err != nil {return err}
+  }
+
+  if e.Rest != nil {
+    rest := LoxInstance{nil, make(map[string]any), new(bool), nil, nil}
+    for name, field := range inst.Fields {
+      if !taken[name] {rest.Fields[name] = field}
+    }
+    return bind(*e.Rest, rest)
+  }
+
+  return nil
+}
+
+func patternDefault(env environment.Environment, def Expr, value any) (any, error) {
+  if value != nil || def == nil {return value, nil}
+  return evaluate(def, env)
+}
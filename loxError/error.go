@@ -21,6 +21,10 @@ func TokenError(tokeny token.Token, message string) {
     }
 }
 
+func Warning(tokeny token.Token, message string) {
+	os.Stderr.WriteString(fmt.Sprintf("[line %d] Warning at '%s': %s\n", tokeny.Line, tokeny.Lexeme, message))
+}
+
 func Report(line int, where string, message string) {
 	os.Stderr.WriteString(fmt.Sprintf("[line %d] Error %s: %s\n", line, where, message))
 	HadError = true
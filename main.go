@@ -9,25 +9,55 @@ import (
 	"lox/scan"
 	"lox/token"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func main() {
-	if len(os.Args) - 1 > 1 {
-		fmt.Println("Usage: jlox [script]");
-		os.Exit(64);
-	} else if len(os.Args) - 1 == 1 {
-		runFile(os.Args[1])
+	var args []string
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if len(args) == 1 {
+			interpret.Args = os.Args[i:]
+			break
+		} else if arg == "--typecheck" {
+			interpret.StrictTypes = true
+		} else if arg == "--no-contracts" {
+			interpret.CheckContracts = false
+		} else if arg == "--seed" || strings.HasPrefix(arg, "--seed=") {
+			value, found := strings.CutPrefix(arg, "--seed=")
+			if !found && i + 1 < len(os.Args) {
+				i++
+				value = os.Args[i]
+			}
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {usage()}
+			interpret.SeedRandom(seed)
+		} else {
+			args = append(args, arg)
+		}
+	}
+	interpret.DefineNatives()
+
+	if len(args) == 1 {
+		runFile(args[0])
 	} else {
 		runPrompt();
 	}
 }
 
+func usage() {
+	fmt.Println("Usage: jlox [--typecheck] [--no-contracts] [--seed n] [script [args...]]");
+	os.Exit(64);
+}
+
 func runFile(path string) {
 	code, err := os.ReadFile(path)
 	if err == nil {
 		run(string(code))
 	}
 
+	if (interpret.ExitCode >= 0) {os.Exit(interpret.ExitCode)}
 	if (loxError.HadError) {os.Exit(65)}
 	if (loxError.HadRuntimeError) {os.Exit(70)}
 }
@@ -42,6 +72,7 @@ func runPrompt() {
 		}
 
 		run(string(line))
+		if (interpret.ExitCode >= 0) {os.Exit(interpret.ExitCode)}
 		loxError.HadError = false
 	}
 }
@@ -61,5 +92,8 @@ func run(source string) {
 	interpret.InitialResolve(interpret.GlobalEnv, statements)
 	if loxError.HadError {return}
 
+	interpret.TypeCheck(statements)
+	if loxError.HadError {return}
+
 	interpret.Interpret(statements)
 }
@@ -0,0 +1,290 @@
+package parse
+
+import (
+    "fmt"
+    . "lox/interpret"
+    "lox/token"
+    "strings"
+)
+
+// Macros are expanded on the token stream: an invocation `name!(a, b)` is
+// replaced by the macro's body with each parameter swapped for the tokens of
+// its argument, and parsing carries on over the result.
+
+const maxExpansions = 10000
+
+var macros = make(map[string]Macro)
+var expansions int
+var gensyms int
+
+// Spliced tokens get offsets past the end of the source so that every token
+// in the program stays distinct.
+var nextOffset = 1 << 30
+
+func macroDeclaration() (Stmt, error) {
+    name, err := consume(token.IDENTIFIER, "Expect macro name.")
+    if err != nil {return nil, err}
+    _, err = consume(token.LEFT_PAREN, "Expect '(' after macro name.")
+    if err != nil {return nil, err}
+
+    var params []token.Token
+    if !check(token.RIGHT_PAREN) {
+        for commad := true; commad; commad = match(token.COMMA) {
+            param, err := consume(token.IDENTIFIER, "Expect parameter name.")
+            if err != nil {return nil, err}
+            params = append(params, param)
+        }
+    }
+    _, err = consume(token.RIGHT_PAREN, "Expect ')' after parameters.")
+    if err != nil {return nil, err}
+    _, err = consume(token.LEFT_BRACE, "Expect '{' before macro body.")
+    if err != nil {return nil, err}
+
+    var body []token.Token
+    for depth := 0; depth > 0 || !check(token.RIGHT_BRACE); {
+        if isAtEnd() {return nil, parseError(peek(), "Unterminated macro body.")}
+        switch advance().TokenType {
+        case token.LEFT_BRACE:
+            depth++
+        case token.RIGHT_BRACE:
+            depth--
+        }
+        body = append(body, previous())
+    }
+    advance()
+
+    macro := Macro{name, params, body}
+    macros[name.Lexeme] = macro
+    return macro, nil
+}
+
+func isMacroCall() bool {
+    if current + 2 >= len(tokens) || !check(token.IDENTIFIER) {return false}
+    return tokens[current + 1].TokenType == token.BANG && tokens[current + 2].TokenType == token.LEFT_PAREN
+}
+
+// expandMacros replaces any macro invocations at the current token until the
+// stream starts with something else. A statement-level invocation whose
+// expansion is already a complete statement may be followed by a ';', which
+// is dropped.
+func expandMacros(statement bool) error {
+    for isMacroCall() {
+        name := peek()
+        macro, ok := macros[name.Lexeme]
+        if !ok {return parseError(name, fmt.Sprintf("Undefined macro '%s'.", name.Lexeme))}
+
+        expansions++
+        if expansions > maxExpansions {
+            return parseError(name, "Too many macro expansions; is a macro expanding itself?")
+        }
+
+        start := current
+        current += 3
+        arguments, err := macroArguments()
+        if err != nil {return err}
+        if len(arguments) != len(macro.Params) {
+            return parseError(name, fmt.Sprintf("Macro '%s' expects %d arguments but got %d.", name.Lexeme, len(macro.Params), len(arguments)))
+        }
+
+        expansion := expand(macro, arguments)
+        if statement && check(token.SEMICOLON) && len(expansion) > 0 {
+            last := expansion[len(expansion) - 1].TokenType
+            if last == token.SEMICOLON || last == token.RIGHT_BRACE {advance()}
+        }
+        rest := append(expansion, tokens[current:]...)
+        tokens = append(tokens[:start:start], rest...)
+        current = start
+    }
+    return nil
+}
+
+// Arguments are split on commas outside of any brackets.
+func macroArguments() ([][]token.Token, error) {
+    var arguments [][]token.Token
+    var argument []token.Token
+    depth := 0
+    for depth > 0 || !check(token.RIGHT_PAREN) {
+        if isAtEnd() {return nil, parseError(peek(), "Expect ')' after macro arguments.")}
+
+        next := advance()
+        switch next.TokenType {
+        case token.LEFT_PAREN, token.LEFT_BRACE, token.LEFT_BRACKET:
+            depth++
+        case token.RIGHT_PAREN, token.RIGHT_BRACE, token.RIGHT_BRACKET:
+            depth--
+        case token.COMMA:
+            if depth == 0 {
+                arguments = append(arguments, argument)
+                argument = nil
+                continue
+            }
+        }
+        argument = append(argument, next)
+    }
+    advance()
+
+    if argument != nil || len(arguments) > 0 {arguments = append(arguments, argument)}
+    return arguments, nil
+}
+
+func expand(macro Macro, arguments [][]token.Token) []token.Token {
+    bound := make(map[string][]token.Token)
+    for i, param := range macro.Params {
+        bound[param.Lexeme] = arguments[i]
+    }
+
+    renamed := make(map[string]string)
+    for _, name := range introducedNames(macro.Body) {
+        if _, ok := bound[name]; ok {continue}
+        gensyms++
+        renamed[name] = fmt.Sprintf("%s#%d", name, gensyms)
+    }
+
+    var out []token.Token
+    for i := 0; i < len(macro.Body); i++ {
+        tok := macro.Body[i]
+        afterDot := i > 0 && macro.Body[i - 1].TokenType == token.DOT
+
+        if tok.TokenType == token.HASH && i + 1 < len(macro.Body) {
+            argument, ok := bound[macro.Body[i + 1].Lexeme]
+            if ok {
+                text := sourceText(argument)
+                out = append(out, spliced(token.Token{token.STRING, "\"" + text + "\"", text, tok.Line, 0}))
+                i++
+                continue
+            }
+        }
+
+        if tok.TokenType == token.IDENTIFIER && !afterDot {
+            argument, ok := bound[tok.Lexeme]
+            if ok {
+                for _, argumentToken := range argument {
+                    out = append(out, spliced(argumentToken))
+                }
+                continue
+            }
+            name, ok := renamed[tok.Lexeme]
+            if ok {tok.Lexeme = name}
+        }
+        out = append(out, spliced(tok))
+    }
+    return out
+}
+
+func spliced(tok token.Token) token.Token {
+    tok.Offset = nextOffset
+    nextOffset++
+    return tok
+}
+
+// introducedNames finds the variables, functions, classes and parameters a
+// macro body declares. These are renamed on each expansion so they can
+// neither capture nor be captured by the caller's variables. Only names in
+// binding positions count; default values and annotations are skipped.
+func introducedNames(body []token.Token) []string {
+    var names []string
+    for i := 0; i + 1 < len(body); i++ {
+        switch body[i].TokenType {
+        case token.VAR, token.CONST, token.VAL:
+            found, _ := patternNames(body, i + 1)
+            names = append(names, found...)
+        case token.FUN, token.CLASS:
+            if body[i + 1].TokenType != token.IDENTIFIER {continue}
+            names = append(names, body[i + 1].Lexeme)
+            if body[i].TokenType == token.CLASS || i + 2 >= len(body) || body[i + 2].TokenType != token.LEFT_PAREN {continue}
+
+            found, _ := elementNames(body, i + 3, false)
+            names = append(names, found...)
+        }
+    }
+    return names
+}
+
+// patternNames returns the names bound by the pattern at body[i], and the
+// index just past it and any annotation or default value.
+func patternNames(body []token.Token, i int) ([]string, int) {
+    if i >= len(body) {return nil, i}
+
+    var names []string
+    switch body[i].TokenType {
+    case token.IDENTIFIER:
+        names = append(names, body[i].Lexeme)
+        i++
+        if i < len(body) && body[i].TokenType == token.COLON {i = skipExpression(body, i + 1, true)}
+    case token.LEFT_BRACKET:
+        names, i = elementNames(body, i + 1, false)
+    case token.LEFT_BRACE:
+        names, i = elementNames(body, i + 1, true)
+    default:
+        return nil, i
+    }
+    if i < len(body) && body[i].TokenType == token.EQUAL {i = skipExpression(body, i + 1, false)}
+    return names, i
+}
+
+// elementNames collects the names bound by the elements of a list pattern,
+// object pattern or parameter list, and returns the index past its closing
+// delimiter. In an object pattern the key before a ':' isn't bound.
+func elementNames(body []token.Token, i int, object bool) ([]string, int) {
+    var names []string
+    for i < len(body) && !isCloser(body[i].TokenType) {
+        var found []string
+        next := i
+        if body[i].TokenType == token.ELLIPSIS {
+            found, next = patternNames(body, i + 1)
+        } else if object && i + 1 < len(body) && body[i].TokenType == token.IDENTIFIER && body[i + 1].TokenType == token.COLON {
+            found, next = patternNames(body, i + 2)
+        } else {
+            found, next = patternNames(body, i)
+        }
+        if next == i {next = skipExpression(body, i + 1, false)}
+
+        names = append(names, found...)
+        i = next
+        if i < len(body) && body[i].TokenType == token.COMMA {i++}
+    }
+    return names, i + 1
+}
+
+// skipExpression returns the index of the first ',', ';' or unmatched closing
+// delimiter from body[i], or of the first '=' if skipping an annotation.
+func skipExpression(body []token.Token, i int, annotation bool) int {
+    depth := 0
+    for ; i < len(body); i++ {
+        tokenType := body[i].TokenType
+        if depth == 0 && (tokenType == token.COMMA || tokenType == token.SEMICOLON || isCloser(tokenType) || (annotation && tokenType == token.EQUAL)) {
+            break
+        }
+        switch tokenType {
+        case token.LEFT_PAREN, token.LEFT_BRACE, token.LEFT_BRACKET:
+            depth++
+        case token.RIGHT_PAREN, token.RIGHT_BRACE, token.RIGHT_BRACKET:
+            depth--
+        }
+    }
+    return i
+}
+
+func isCloser(tokenType token.TokenType) bool {
+    return tokenType == token.RIGHT_PAREN || tokenType == token.RIGHT_BRACE || tokenType == token.RIGHT_BRACKET
+}
+
+// originalName undoes the renaming of an introduced name, for where the
+// name is also a property key.
+func originalName(name string) string {
+    before, _, _ := strings.Cut(name, "#")
+    return before
+}
+
+// sourceText rebuilds an argument's source from its tokens, keeping a single
+// space wherever the original had any whitespace.
+func sourceText(argument []token.Token) string {
+    builder := strings.Builder{}
+    for i, tok := range argument {
+        if i > 0 && tok.Offset != argument[i - 1].Offset + len(argument[i - 1].Lexeme) {
+            builder.WriteString(" ")
+        }
+        builder.WriteString(tok.Lexeme)
+    }
+    return builder.String()
+}
@@ -0,0 +1,65 @@
+package parse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"lox/interpret"
+	"lox/loxError"
+	"lox/scan"
+)
+
+func TestIntroducedNames(t *testing.T) {
+    tests := []struct {
+        name string
+        body string
+        want []string
+    }{
+        {"variable", "var x = limit;", []string{"x"}},
+        {"annotated variable", "var x: fun(Number): String = f;", []string{"x"}},
+        {"constant", "const x = 1; val y = 2;", []string{"x", "y"}},
+        {"list pattern", "var [a, b = limit, ...rest] = xs;", []string{"a", "b", "rest"}},
+        {"nested list pattern", "var [a, [b, c = d]] = xs;", []string{"a", "b", "c"}},
+        {"object pattern", "var {a, b = limit, c: d, e: [f, g = h], ...rest} = o;", []string{"a", "b", "d", "f", "g", "rest"}},
+        {"parameters", "fun helper(n = limit, m: Number, ...rest) {}", []string{"helper", "n", "m", "rest"}},
+        {"parameter with call default", "fun helper(n = f(a, b), m) {}", []string{"helper", "n", "m"}},
+        {"destructured parameters", "fun helper([a, b = limit], {c, d: e}) {}", []string{"helper", "a", "b", "c", "e"}},
+        {"class", "class C { m(x) {} }", []string{"C"}},
+    }
+    for _, test := range tests {
+        tokens := scan.ScanTokens(scan.NewScanner(test.body))
+        got := introducedNames(tokens[:len(tokens) - 1])
+        if !reflect.DeepEqual(got, test.want) {
+            t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+        }
+    }
+}
+
+// A renamed shorthand property still reads the property it was written as.
+func TestObjectPatternInMacroKeepsPropertyName(t *testing.T) {
+    statements := parseSource(t, "macro m() { var {k} = o; } m!();")
+    declaration, ok := statements[1].(interpret.Var)
+    if !ok {t.Fatalf("expected a var declaration, got %#v", statements[1])}
+    property := declaration.Pattern.(interpret.ObjectPattern).Properties[0]
+    name := property.Value.(interpret.NamePattern).Name.Lexeme
+    if property.Key.Lexeme != "k" || !strings.HasPrefix(name, "k#") {
+        t.Errorf("got key %q bound to %q, want key \"k\" bound to a renamed name", property.Key.Lexeme, name)
+    }
+}
+
+// The ';' after a statement-level invocation is dropped when the expansion
+// already ends a statement, even if its last token came from an argument.
+func TestMacroSemicolonAfterCompleteExpansion(t *testing.T) {
+    tests := []string{
+        "macro unless(c, body) { if (!(c)) body } unless!(false, { print 1; });",
+        "macro twice(s) { s s } twice!(print 1;);",
+        "macro show(e) { print e; } show!(1);",
+    }
+    for _, source := range tests {
+        parseSource(t, source)
+        if loxError.HadError {
+            t.Errorf("%q: unexpected parse error", source)
+        }
+    }
+}
@@ -11,10 +11,17 @@ import (
 
 var tokens []token.Token
 var current int
+var inEnsures bool
+// inThenBranch is set while parsing the then branch of an if outside any
+// braces, where an else belongs to the if rather than to a loop.
+var inThenBranch bool
+var olds []Old
 
 func Parse(p_tokens []token.Token) []Stmt {
     tokens = p_tokens
     current = 0
+    expansions = 0
+    inThenBranch = false
     var statements []Stmt
     
     for !isAtEnd() {
@@ -28,12 +35,24 @@ func declaration() Stmt {
     var err error
     var out Stmt
 
-    if match(token.CLASS) {
+    err = expandMacros(true)
+    if err != nil {
+        synchronize()
+        return nil
+    }
+
+    if match(token.MACRO) {
+        out, err = macroDeclaration()
+    } else if check(token.AT) {
+        out, err = decoratedDeclaration()
+    } else if match(token.CLASS) {
         out, err = classDeclaration()
     } else if match(token.FUN) {
         out, err = function("function")
     } else if match(token.VAR) {
         out, err = varDeclaration()
+    } else if match(token.CONST, token.VAL) {
+        out, err = constDeclaration()
     } else {
         out, err = statement()
     }
@@ -46,33 +65,119 @@ func declaration() Stmt {
     return out
 }
 
+func decoratedDeclaration() (Stmt, error) {
+    decorators, err := decoratorList()
+    if err != nil {return nil, err}
+
+    if match(token.CLASS) {
+        out, err := classDeclaration()
+        if err != nil {return nil, err}
+        class := out.(Class)
+        class.Decorators = decorators
+        return class, nil
+    }
+
+    _, err = consume(token.FUN, "Expect function or class after decorator.")
+    if err != nil {return nil, err}
+    fun, err := function("function")
+    if err != nil {return nil, err}
+    fun.Decorators = decorators
+    return fun, nil
+}
+
+func decoratorList() ([]Decorator, error) {
+    var decorators []Decorator
+    for match(token.AT) {
+        at := previous()
+        expr, err := call()
+        if err != nil {return nil, err}
+        decorators = append(decorators, Decorator{at, expr})
+    }
+    return decorators, nil
+}
+
 func classDeclaration() (Stmt, error) {
     name, err := consume(token.IDENTIFIER, "Expect class name.")
     if err != nil {return nil, err}
+    return classBody(name)
+}
+
+// A class expression may leave out its name, which is then only used when
+// printing the class.
+func classExpression() (Expr, error) {
+    name := previous()
+    if check(token.IDENTIFIER) {
+        name = advance()
+    } else {
+        name.Lexeme = "anonymous"
+    }
+
+    class, err := classBody(name)
+    if err != nil {return nil, err}
+    return ClassExpr{class}, nil
+}
 
+func classBody(name token.Token) (Class, error) {
     var superclass *interpret.Variable
     if match(token.LESS) {
         consume(token.IDENTIFIER, "Expect superclass name.")
         superclass = &interpret.Variable{previous()}
     }
     
-    _, err = consume(token.LEFT_BRACE, "Expect '{' before class body.")
-    if err != nil {return nil, err}
+    _, err := consume(token.LEFT_BRACE, "Expect '{' before class body.")
+    if err != nil {return Class{}, err}
 
     var methods []Function
     var staticMethods []Function
     var getters []Function
+    var fields []Field
+    var classes []Class
     for !check(token.RIGHT_BRACE) && !isAtEnd() {
+        decorators, err := decoratorList()
+        if err != nil {return Class{}, err}
+
         var class = check(token.CLASS)
         if class {consume(token.CLASS, "")}
 
+        if class && check(token.IDENTIFIER) && (doublePeek().TokenType == token.LEFT_BRACE || doublePeek().TokenType == token.LESS) {
+            nested, err := classBody(advance())
+            if err != nil {return Class{}, err}
+            nested.Decorators = decorators
+            classes = append(classes, nested)
+            continue
+        }
+
+        if !class && check(token.IDENTIFIER) && doublePeek().TokenType == token.COLON {
+            if len(decorators) > 0 {
+                return Class{}, parseError(decorators[0].At, "Fields and getters can't be decorated.")
+            }
+            name := advance()
+            advance()
+            typeExpr, err := typeAnnotation()
+            if err != nil {return Class{}, err}
+
+            if match(token.SEMICOLON) {
+                fields = append(fields, Field{name, typeExpr})
+                continue
+            }
+
+            getter, err := functionBody("getter", name, nil, nil, typeExpr)
+            if err != nil {return Class{}, err}
+            getters = append(getters, getter)
+            continue
+        }
+
         funcType := "method"
         var getter = doublePeek().TokenType != token.LEFT_PAREN
         if getter {
             funcType = "getter"
         }
         fun, err := function(funcType)
-        if err != nil {return fun, err}
+        if err != nil {return Class{}, err}
+        if getter && len(decorators) > 0 {
+            return Class{}, parseError(decorators[0].At, "Fields and getters can't be decorated.")
+        }
+        fun.Decorators = decorators
         
         if class {
             staticMethods = append(staticMethods, fun)
@@ -84,16 +189,17 @@ func classDeclaration() (Stmt, error) {
     }
 
     _, err = consume(token.RIGHT_BRACE, "Expect '}' after class body.")
-    if err != nil {return nil, err}
+    if err != nil {return Class{}, err}
 
-    return Class{name, superclass, methods, staticMethods, getters}, nil
+    return Class{name, superclass, methods, staticMethods, getters, fields, classes, nil}, nil
 }
 
 func function(kind string) (Function, error) {
     name, err := consume(token.IDENTIFIER, fmt.Sprintf("Expect %s name.", kind))
     if err != nil {return Function{}, err}
 
-    var parameters []token.Token
+    var parameters []Pattern
+    var rest *token.Token
     if kind != "getter" {
         consume(token.LEFT_PAREN, fmt.Sprintf("Expect '(' after %s name", kind))
         if !check(token.RIGHT_PAREN) {
@@ -102,8 +208,22 @@ func function(kind string) (Function, error) {
                     loxError.TokenError(peek(), "Can't have more than 255 parameters.")
                 }
 
-                toAdd, err := consume(token.IDENTIFIER, "Expect parameter name.")
+                if match(token.ELLIPSIS) {
+                    name, err := consume(token.IDENTIFIER, "Expect parameter name after '...'.")
+                    if err != nil {return Function{}, err}
+                    rest = &name
+                    break
+                }
+
+                toAdd, err := pattern("Expect parameter name.")
                 if err != nil {return Function{}, err}
+                toAdd, err = annotate(toAdd)
+                if err != nil {return Function{}, err}
+                if match(token.EQUAL) {
+                    def, err := expression()
+                    if err != nil {return Function{}, err}
+                    toAdd = withDefault(toAdd, def)
+                }
                 parameters = append(parameters, toAdd)
             }
         }
@@ -111,42 +231,375 @@ func function(kind string) (Function, error) {
         if err != nil {return Function{}, err}
     }
 
-    _, err = consume(token.LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body", kind))
+    var returnType TypeExpr
+    if match(token.COLON) {
+        returnType, err = typeAnnotation()
+        if err != nil {return Function{}, err}
+    }
+
+    return functionBody(kind, name, parameters, rest, returnType)
+}
+
+func functionBody(kind string, name token.Token, parameters []Pattern, rest *token.Token, returnType TypeExpr) (Function, error) {
+    var requires []Contract
+    for match(token.REQUIRES) {
+        clause, err := contract()
+        if err != nil {return Function{}, err}
+        requires = append(requires, clause)
+    }
+
+    olds = nil
+    var ensures []Contract
+    for match(token.ENSURES) {
+        inEnsures = true
+        clause, err := contract()
+        inEnsures = false
+        if err != nil {return Function{}, err}
+        ensures = append(ensures, clause)
+    }
+    snapshots := olds
+
+    _, err := consume(token.LEFT_BRACE, fmt.Sprintf("Expect '{' before %s body", kind))
     if err != nil {return Function{}, err}
 
     body := block()
-    return Function{name, parameters, body}, nil
+    return Function{name, parameters, rest, returnType, body, nil, requires, ensures, snapshots}, nil
+}
+
+func contract() (Contract, error) {
+    keyword := previous()
+    start := current
+    condition, err := expression()
+    if err != nil {return Contract{}, err}
+    source := sourceText(tokens[start:current])
+
+    var message Expr
+    if match(token.COMMA) {
+        message, err = expression()
+        if err != nil {return Contract{}, err}
+    }
+    return Contract{keyword, condition, message, source}, nil
+}
+
+func annotate(target Pattern) (Pattern, error) {
+    name, ok := target.(NamePattern)
+    if !ok || !match(token.COLON) {return target, nil}
+
+    typeExpr, err := typeAnnotation()
+    if err != nil {return nil, err}
+    name.Type = typeExpr
+    return name, nil
+}
+
+func typeAnnotation() (TypeExpr, error) {
+    var typeExpr TypeExpr
+    if match(token.FUN) {
+        keyword := previous()
+        _, err := consume(token.LEFT_PAREN, "Expect '(' after 'fun' in function type.")
+        if err != nil {return nil, err}
+
+        var params []TypeExpr
+        if !check(token.RIGHT_PAREN) {
+            for commad := true; commad; commad = match(token.COMMA) {
+                param, err := typeAnnotation()
+                if err != nil {return nil, err}
+                params = append(params, param)
+            }
+        }
+        _, err = consume(token.RIGHT_PAREN, "Expect ')' after function type parameters.")
+        if err != nil {return nil, err}
+
+        var result TypeExpr
+        if match(token.COLON) {
+            result, err = typeAnnotation()
+            if err != nil {return nil, err}
+        }
+        typeExpr = CallableType{keyword, params, result}
+    } else if match(token.IDENTIFIER, token.NIL) {
+        typeExpr = NamedType{previous()}
+    } else {
+        return nil, parseError(peek(), "Expect type.")
+    }
+
+    if match(token.QUESTION) {
+        typeExpr = NullableType{typeExpr, previous()}
+    }
+    return typeExpr, nil
 }
 
 func varDeclaration() (Stmt, error) {
-    name, err := consume(token.IDENTIFIER, "Expect variable name.")
+    target, err := pattern("Expect variable name.")
+    if err != nil {return nil, err}
+    target, err = annotate(target)
     if err != nil {return nil, err}
 
+    if check(token.COMMA) {
+        targets := []Pattern{target}
+        comma := peek()
+        for match(token.COMMA) {
+            next, err := pattern("Expect variable name.")
+            if err != nil {return nil, err}
+            next, err = annotate(next)
+            if err != nil {return nil, err}
+            targets = append(targets, next)
+        }
+        target = ListPattern{comma, targets, nil, nil}
+    }
+
     var initializer Expr
     if match(token.EQUAL) {
-        initializer, err = expression()
+        initializer, err = expressionList()
         if err != nil {return nil, err}
+    } else if _, ok := target.(NamePattern); !ok {
+        return nil, parseError(peek(), "Expect '=' after destructuring pattern.")
     }
 
     _, err = consume(token.SEMICOLON, "Expect ';' after variable declaration.")
     if err != nil {return nil, err}
 
-    return Var{name, initializer}, nil
+    return Var{target, initializer, false}, nil
+}
+
+func constDeclaration() (Stmt, error) {
+    keyword := previous()
+    target, err := pattern("Expect constant name.")
+    if err != nil {return nil, err}
+    target, err = annotate(target)
+    if err != nil {return nil, err}
+
+    _, err = consume(token.EQUAL, fmt.Sprintf("Expect '=' after '%s' name; constants must be initialised.", keyword.Lexeme))
+    if err != nil {return nil, err}
+
+    initializer, err := expressionList()
+    if err != nil {return nil, err}
+
+    _, err = consume(token.SEMICOLON, "Expect ';' after constant declaration.")
+    if err != nil {return nil, err}
+
+    return Var{target, initializer, true}, nil
+}
+
+func pattern(message string) (Pattern, error) {
+    if match(token.LEFT_BRACKET) {return listPattern()}
+    if match(token.LEFT_BRACE) {return objectPattern()}
+
+    name, err := consume(token.IDENTIFIER, message)
+    if err != nil {return nil, err}
+    return NamePattern{name, nil, nil}, nil
+}
+
+func patternElement() (Pattern, error) {
+    element, err := pattern("Expect variable name in pattern.")
+    if err != nil {return nil, err}
+
+    if match(token.EQUAL) {
+        def, err := expression()
+        if err != nil {return nil, err}
+        element = withDefault(element, def)
+    }
+    return element, nil
+}
+
+func listPattern() (Pattern, error) {
+    bracket := previous()
+    var elements []Pattern
+    var rest *token.Token
+
+    if !check(token.RIGHT_BRACKET) {
+        for commad := true; commad; commad = match(token.COMMA) {
+            if match(token.ELLIPSIS) {
+                name, err := consume(token.IDENTIFIER, "Expect name after '...'.")
+                if err != nil {return nil, err}
+                rest = &name
+                break
+            }
+
+            element, err := patternElement()
+            if err != nil {return nil, err}
+            elements = append(elements, element)
+        }
+    }
+
+    _, err := consume(token.RIGHT_BRACKET, "Expect ']' after list pattern.")
+    if err != nil {return nil, err}
+
+    return ListPattern{bracket, elements, rest, nil}, nil
+}
+
+func objectPattern() (Pattern, error) {
+    brace := previous()
+    var properties []PropertyPattern
+    var rest *token.Token
+
+    if !check(token.RIGHT_BRACE) {
+        for commad := true; commad; commad = match(token.COMMA) {
+            if match(token.ELLIPSIS) {
+                name, err := consume(token.IDENTIFIER, "Expect name after '...'.")
+                if err != nil {return nil, err}
+                rest = &name
+                break
+            }
+
+            key, err := consume(token.IDENTIFIER, "Expect property name in pattern.")
+            if err != nil {return nil, err}
+            property := key
+            property.Lexeme = originalName(key.Lexeme)
+
+            var value Pattern = NamePattern{key, nil, nil}
+            if match(token.COLON) {
+                value, err = patternElement()
+                if err != nil {return nil, err}
+            } else if match(token.EQUAL) {
+                def, err := expression()
+                if err != nil {return nil, err}
+                value = NamePattern{key, def, nil}
+            }
+            properties = append(properties, PropertyPattern{property, value})
+        }
+    }
+
+    _, err := consume(token.RIGHT_BRACE, "Expect '}' after object pattern.")
+    if err != nil {return nil, err}
+
+    return ObjectPattern{brace, properties, rest, nil}, nil
+}
+
+func withDefault(element Pattern, def Expr) Pattern {
+    switch p := element.(type) {
+    case NamePattern:
+        p.Default = def
+        return p
+    case ListPattern:
+        p.Default = def
+        return p
+    case ObjectPattern:
+        p.Default = def
+        return p
+    }
+    return element
+}
+
+// Converts a list literal on the left of '=' back into the pattern it spells.
+func listTarget(list List) (Pattern, error) {
+    var elements []Pattern
+    var rest *token.Token
+
+    for i, element := range list.Elements {
+        spread, ok := element.(Spread)
+        if ok {
+            v, ok := spread.Expression.(Variable)
+            if !ok || i != len(list.Elements) - 1 {
+                return nil, parseError(spread.Ellipsis, "Invalid destructuring target.")
+            }
+            rest = &v.Name
+            break
+        }
+
+        target, err := assignmentTarget(list.Bracket, element)
+        if err != nil {return nil, err}
+        elements = append(elements, target)
+    }
+
+    return ListPattern{list.Bracket, elements, rest, nil}, nil
+}
+
+func assignmentTarget(bracket token.Token, expr Expr) (Pattern, error) {
+    switch e := expr.(type) {
+    case Variable:
+        return NamePattern{e.Name, nil, nil}, nil
+    case Assign:
+        return NamePattern{e.Name, e.Value, nil}, nil
+    case List:
+        return listTarget(e)
+    case Destructure:
+        return withDefault(e.Pattern, e.Value), nil
+    }
+    return nil, parseError(bracket, "Invalid destructuring target.")
+}
+
+func isObjectDestructuring() bool {
+    depth := 0
+    for i := current; i < len(tokens); i++ {
+        switch tokens[i].TokenType {
+        case token.LEFT_BRACE, token.LEFT_BRACKET, token.LEFT_PAREN:
+            depth++
+        case token.RIGHT_BRACE, token.RIGHT_BRACKET, token.RIGHT_PAREN:
+            depth--
+            if depth == 0 {
+                return i + 1 < len(tokens) && tokens[i + 1].TokenType == token.EQUAL
+            }
+        case token.SEMICOLON, token.EOF:
+            return false
+        }
+    }
+    return false
+}
+
+func destructuringStatement() (Stmt, error) {
+    consume(token.LEFT_BRACE, "Expect '{' before object pattern.")
+    target, err := objectPattern()
+    if err != nil {return nil, err}
+
+    _, err = consume(token.EQUAL, "Expect '=' after object pattern.")
+    if err != nil {return nil, err}
+
+    value, err := expression()
+    if err != nil {return nil, err}
+
+    _, err = consume(token.SEMICOLON, "Expect ';' after expression.")
+    if err != nil {return nil, err}
+
+    return Expression{Destructure{target, value}}, nil
 }
 
 
 
 func statement() (Stmt, error) {
+    err := expandMacros(true)
+    if err != nil {return nil, err}
+
     if match(token.FOR) {return forStatement()}
     if match(token.IF) {return ifStatement()}
     if match(token.PRINT) {return printStatement()}
     if match(token.RETURN) {return returnStatement()}
     if match(token.WHILE) {return whileStatement()}
+    if match(token.DO) {return doWhileStatement()}
+    if match(token.SWITCH) {return switchStatement()}
+    if check(token.LEFT_BRACE) && isObjectDestructuring() {return destructuringStatement()}
     if match(token.LEFT_BRACE) {return Block{block()}, nil}
     if match(token.BREAK) {return breakStatement()}
+    if match(token.DEFER) {return deferStatement()}
+    if match(token.ASSERT) {return assertStatement()}
+    if match(token.FALLTHROUGH) {
+        return nil, parseError(previous(), "Can only fall through at the end of a switch case.")
+    }
     return expressionStatement()
 }
 
+func assertStatement() (Stmt, error) {
+    clause, err := contract()
+    if err != nil {return nil, err}
+    _, err = consume(token.SEMICOLON, "Expect ';' after assertion.")
+    if err != nil {return nil, err}
+
+    return Assert(clause), nil
+}
+
+func deferStatement() (Stmt, error) {
+    keyword := previous()
+    expr, err := expression()
+    if err != nil {return nil, err}
+
+    call, ok := expr.(Call)
+    if !ok {
+        return nil, parseError(keyword, "Expect a call after 'defer'.")
+    }
+    _, err = consume(token.SEMICOLON, "Expect ';' after deferred call.")
+    if err != nil {return nil, err}
+
+    return Defer{keyword, call}, nil
+}
+
 func breakStatement() (Stmt, error) {
     _, err := consume(token.SEMICOLON, "Expect ';' after break statement.")
     if err != nil {return nil, err}
@@ -184,13 +637,15 @@ func forStatement() (Stmt, error) {
     
     body, err := statement()
     if err != nil {return body, err}
+    elseBranch, err := loopElse()
+    if err != nil {return nil, err}
 
     if increment != nil {
         body = Block{[]Stmt{body, Expression{increment}}}
     }
     
     if condition == nil {condition = Literal{true}}
-    body = While{condition, body}
+    body = While{condition, body, elseBranch}
 
     if initializer != nil {
         body = Block{[]Stmt{initializer, body}}
@@ -207,8 +662,96 @@ func whileStatement() (Stmt, error) {
     consume(token.RIGHT_PAREN, "Expect ')' after 'condition'.")
     body, err := statement()
     if err != nil {return body, err}
+    elseBranch, err := loopElse()
+    if err != nil {return nil, err}
+
+    return While{condition, body, elseBranch}, nil
+}
+
+// The else branch of a loop runs only when the loop wasn't left by a break.
+// A loop in the then branch of an if leaves its else to the if, as it always
+// has; such a loop needs braces around it to take an else of its own.
+func loopElse() (Stmt, error) {
+    if inThenBranch || !match(token.ELSE) {return nil, nil}
+    return statement()
+}
+
+func doWhileStatement() (Stmt, error) {
+    body, err := statement()
+    if err != nil {return nil, err}
 
-    return While{condition, body}, nil
+    _, err = consume(token.WHILE, "Expect 'while' after do body.")
+    if err != nil {return nil, err}
+    _, err = consume(token.LEFT_PAREN, "Expect '(' after 'while'.")
+    if err != nil {return nil, err}
+    condition, err := expression()
+    if err != nil {return nil, err}
+    _, err = consume(token.RIGHT_PAREN, "Expect ')' after condition.")
+    if err != nil {return nil, err}
+    _, err = consume(token.SEMICOLON, "Expect ';' after do-while statement.")
+    if err != nil {return nil, err}
+
+    return DoWhile{body, condition}, nil
+}
+
+func switchStatement() (Stmt, error) {
+    keyword := previous()
+    _, err := consume(token.LEFT_PAREN, "Expect '(' after 'switch'.")
+    if err != nil {return nil, err}
+    subject, err := expression()
+    if err != nil {return nil, err}
+    _, err = consume(token.RIGHT_PAREN, "Expect ')' after switch subject.")
+    if err != nil {return nil, err}
+    _, err = consume(token.LEFT_BRACE, "Expect '{' before switch body.")
+    if err != nil {return nil, err}
+
+    enclosing := inThenBranch
+    inThenBranch = false
+    defer func() {inThenBranch = enclosing}()
+
+    var cases []SwitchCase
+    hasDefault := false
+    for !check(token.RIGHT_BRACE) && !isAtEnd() {
+        label := peek()
+        var values []Expr
+        if match(token.DEFAULT) {
+            if hasDefault {
+                return nil, parseError(previous(), "A switch can only have one default case.")
+            }
+            hasDefault = true
+        } else {
+            _, err = consume(token.CASE, "Expect 'case' or 'default' in switch body.")
+            if err != nil {return nil, err}
+            for commad := true; commad; commad = match(token.COMMA) {
+                value, err := expression()
+                if err != nil {return nil, err}
+                values = append(values, value)
+            }
+        }
+        _, err = consume(token.COLON, "Expect ':' after case.")
+        if err != nil {return nil, err}
+
+        var body []Stmt
+        falls := false
+        for !check(token.CASE) && !check(token.DEFAULT) && !check(token.RIGHT_BRACE) && !isAtEnd() {
+            if match(token.FALLTHROUGH) {
+                keyword := previous()
+                _, err = consume(token.SEMICOLON, "Expect ';' after 'fallthrough'.")
+                if err != nil {return nil, err}
+                if !check(token.CASE) && !check(token.DEFAULT) {
+                    return nil, parseError(keyword, "Can only fall through at the end of a switch case.")
+                }
+                falls = true
+                break
+            }
+            body = append(body, declaration())
+        }
+        cases = append(cases, SwitchCase{label, values, body, falls})
+    }
+
+    _, err = consume(token.RIGHT_BRACE, "Expect '}' after switch body.")
+    if err != nil {return nil, err}
+    return Switch{keyword, subject, cases}, nil
 }
 
 func ifStatement() (Stmt, error) {
@@ -217,7 +760,10 @@ func ifStatement() (Stmt, error) {
     if err != nil {return nil, err}
     consume(token.RIGHT_PAREN, "Expect ')' after if condition.")
 
+    enclosing := inThenBranch
+    inThenBranch = true
     thenBranch, err := statement()
+    inThenBranch = enclosing
     if err != nil {return nil, err}
     var elseBranch Stmt = nil
     if (match(token.ELSE)) {
@@ -231,9 +777,12 @@ func ifStatement() (Stmt, error) {
 func block() []Stmt {
     var statements []Stmt
 
+    enclosing := inThenBranch
+    inThenBranch = false
     for !check(token.RIGHT_BRACE) && !isAtEnd() {
         statements = append(statements, declaration())
     }
+    inThenBranch = enclosing
 
     consume(token.RIGHT_BRACE, "Expect '}' after block.")
     return statements
@@ -252,7 +801,7 @@ func returnStatement() (Stmt, error) {
     var value Expr = nil
     if !check(token.SEMICOLON) {
         var err error
-        value, err = expression()
+        value, err = expressionList()
         if err != nil {return nil, err}
     }
 
@@ -281,11 +830,19 @@ func assignment() (Expr, error) {
 
         v, okv := expr.(Variable)
         i, oki := expr.(Get)
+        x, okx := expr.(Index)
+        l, okl := expr.(List)
         if okv {
             name := v.Name
             return Assign{name, value}, nil
         } else if oki {
             return Set{i.Object, i.Name, value}, nil
+        } else if okx {
+            return SetIndex{x.Object, x.Bracket, x.Index, value}, nil
+        } else if okl {
+            target, err := listTarget(l)
+            if err != nil {return nil, err}
+            return Destructure{target, value}, nil
         }
 
         parseError(equals, "Invalid assignment target.")
@@ -327,6 +884,21 @@ func expression() (Expr, error) {
     return assignment()
 }
 
+func expressionList() (Expr, error) {
+    first, err := expression()
+    if err != nil {return nil, err}
+    if !check(token.COMMA) {return first, nil}
+
+    comma := peek()
+    elements := []Expr{first}
+    for match(token.COMMA) {
+        expr, err := expression()
+        if err != nil {return nil, err}
+        elements = append(elements, expr)
+    }
+    return Tuple{comma, elements}, nil
+}
+
 func ternary() (Expr, error) {
     expression, err := equality()    
     if err != nil {return expression, err}
@@ -364,7 +936,7 @@ func comparison() (Expr, error) {
     expression, err := term()
     if err != nil {return expression, err}
 
-    for match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
+    for match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL, token.IS) {
         operator := previous()
         right, err := term()
         if err != nil {return right, err}
@@ -430,6 +1002,12 @@ func call() (Expr, error) {
             name, err := consume(token.IDENTIFIER, "Expect property name after '.'.")
             if err != nil {return nil, err}
             expr = Get{expr, name}
+        } else if match(token.LEFT_BRACKET) {
+            index, err := expression()
+            if err != nil {return nil, err}
+            bracket, err := consume(token.RIGHT_BRACKET, "Expect ']' after index.")
+            if err != nil {return nil, err}
+            expr = Index{expr, bracket, index}
         } else {
             break
         }
@@ -440,11 +1018,32 @@ func call() (Expr, error) {
 
 func finishCall(callee Expr) (Expr, error) {
     var arguments []Expr
+    var named []NamedArgument
     if !check(token.RIGHT_PAREN) {
         for commad := true; commad; commad = match(token.COMMA) {
-            if (len(arguments) >= 255) {
+            if (len(arguments) + len(named) >= 255) {
                 loxError.TokenError(peek(), "Can't have more than 255 arguments.")
             }
+
+            if check(token.IDENTIFIER) && doublePeek().TokenType == token.COLON {
+                name := advance()
+                advance()
+                expr, err := expression()
+                if err != nil {return expr, err}
+                named = append(named, NamedArgument{name, expr})
+                continue
+            }
+
+            if len(named) > 0 {
+                return nil, parseError(peek(), "Positional argument can't follow named arguments.")
+            }
+            if match(token.ELLIPSIS) {
+                ellipsis := previous()
+                expr, err := expression()
+                if err != nil {return expr, err}
+                arguments = append(arguments, Spread{ellipsis, expr})
+                continue
+            }
             expr, err := expression()
             if err != nil {return expr, err}
             arguments = append(arguments, expr)
@@ -454,10 +1053,31 @@ func finishCall(callee Expr) (Expr, error) {
     paren, err := consume(token.RIGHT_PAREN, "Expect ')' after arguments.")
     if err != nil {return nil, err}
 
-    return Call{callee, paren, arguments}, nil
+    return Call{callee, paren, arguments, named}, nil
+}
+
+// old(expr) in an ensures clause is evaluated when the function is entered.
+func oldExpression() (Expr, error) {
+    keyword := advance()
+    advance()
+    expr, err := expression()
+    if err != nil {return nil, err}
+    _, err = consume(token.RIGHT_PAREN, "Expect ')' after old expression.")
+    if err != nil {return nil, err}
+
+    old := Old{keyword, expr}
+    olds = append(olds, old)
+    return old, nil
 }
 
 func primary() (Expr, error) {
+    err := expandMacros(false)
+    if err != nil {return nil, err}
+
+    if inEnsures && check(token.IDENTIFIER) && peek().Lexeme == "old" && doublePeek().TokenType == token.LEFT_PAREN {
+        return oldExpression()
+    }
+
     if match(token.FALSE) {return Literal{false}, nil}
     if match(token.TRUE) {return Literal{true}, nil}
     if match(token.NIL) {return Literal{nil}, nil}
@@ -466,6 +1086,8 @@ func primary() (Expr, error) {
         return Literal{previous().Literal}, nil
     }
 
+    if match(token.CLASS) {return classExpression()}
+
     if match(token.SUPER) {
         keyword := previous()
         
@@ -486,16 +1108,62 @@ func primary() (Expr, error) {
         return Variable{previous()}, nil
     }
     
+    if match(token.LEFT_BRACKET) {
+        return listLiteral()
+    }
+
     if match(token.LEFT_PAREN) {
-        expression, err := expression()
-        if err != nil {return expression, err}
+        paren := previous()
+        if match(token.RIGHT_PAREN) {return Tuple{paren, nil}, nil}
+
+        first, err := expression()
+        if err != nil {return first, err}
+
+        if match(token.COMMA) {
+            elements := []Expr{first}
+            for !check(token.RIGHT_PAREN) && !isAtEnd() {
+                element, err := expression()
+                if err != nil {return nil, err}
+                elements = append(elements, element)
+                if !match(token.COMMA) {break}
+            }
+            _, err = consume(token.RIGHT_PAREN, "Expect ')' after tuple elements.")
+            if err != nil {return nil, err}
+            return Tuple{paren, elements}, nil
+        }
+
         consume(token.RIGHT_PAREN, "Expect ')' after expression.")
-        return Grouping{expression}, nil
+        return Grouping{first}, nil
     }
 
     return Grouping{}, parseError(peek(), "Expect expression.")
 }
 
+func listLiteral() (Expr, error) {
+    bracket := previous()
+    var elements []Expr
+    if !check(token.RIGHT_BRACKET) {
+        for commad := true; commad; commad = match(token.COMMA) {
+            if match(token.ELLIPSIS) {
+                ellipsis := previous()
+                expr, err := expression()
+                if err != nil {return nil, err}
+                elements = append(elements, Spread{ellipsis, expr})
+                continue
+            }
+
+            expr, err := expression()
+            if err != nil {return nil, err}
+            elements = append(elements, expr)
+        }
+    }
+
+    _, err := consume(token.RIGHT_BRACKET, "Expect ']' after list elements.")
+    if err != nil {return nil, err}
+
+    return List{bracket, elements}, nil
+}
+
 func match(types... token.TokenType) bool {
     for _, t := range types {
         if check(t) {
@@ -552,7 +1220,7 @@ func synchronize() {
         if previous().TokenType == token.SEMICOLON {return}
 
         switch peek().TokenType {
-        case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN:
+        case token.CLASS, token.FUN, token.VAR, token.CONST, token.VAL, token.FOR, token.IF, token.WHILE, token.PRINT, token.RETURN, token.DEFER, token.SWITCH, token.DO, token.MACRO, token.ASSERT:
             return
         }
 
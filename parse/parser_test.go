@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"testing"
+
+	"lox/interpret"
+	"lox/loxError"
+	"lox/scan"
+)
+
+func parseSource(t *testing.T, source string) []interpret.Stmt {
+    t.Helper()
+    loxError.HadError = false
+    t.Cleanup(func() {loxError.HadError = false})
+    return Parse(scan.ScanTokens(scan.NewScanner(source)))
+}
+
+// An else after a loop in the then branch of an if belongs to the if, as it
+// did before loops had else branches.
+func TestElseAfterLoopInIfBelongsToIf(t *testing.T) {
+    tests := []string{
+        "if (c) while (d) s; else t;",
+        "if (c) while (d) { s; } else t;",
+        "if (c) for (;;) { s; } else t;",
+        "if (c) if (d) s; else while (e) { s; } else t;",
+    }
+    for _, source := range tests {
+        statements := parseSource(t, source)
+        if loxError.HadError {
+            t.Errorf("%q: unexpected parse error", source)
+            continue
+        }
+        branch, ok := statements[0].(interpret.If)
+        if !ok || branch.ElseBranch == nil {
+            t.Errorf("%q: expected the else to belong to the if, got %#v", source, statements[0])
+        }
+    }
+}
+
+func TestLoopElse(t *testing.T) {
+    tests := []string{
+        "while (d) { s; } else t;",
+        "while (d) s; else t;",
+        "if (c) { while (d) s; else t; }",
+        "switch (c) { case 1: while (d) s; else t; }",
+    }
+    for _, source := range tests {
+        statements := parseSource(t, source)
+        if loxError.HadError {
+            t.Errorf("%q: unexpected parse error", source)
+            continue
+        }
+        if !hasLoopElse(statements[0]) {
+            t.Errorf("%q: expected a while with an else branch, got %#v", source, statements[0])
+        }
+    }
+}
+
+func hasLoopElse(statement interpret.Stmt) bool {
+    switch s := statement.(type) {
+    case interpret.While:
+        return s.Else != nil
+    case interpret.If:
+        return hasLoopElse(s.ThenBranch)
+    case interpret.Block:
+        return len(s.Statements) > 0 && hasLoopElse(s.Statements[0])
+    case interpret.Switch:
+        return hasLoopElse(s.Cases[0].Body[0])
+    }
+    return false
+}
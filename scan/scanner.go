@@ -22,6 +22,7 @@ var keywords = map[string]TokenType{
   "for": FOR,
   "fun": FUN,
   "if": IF,
+  "is": IS,
   "nil": NIL,
   "or": OR,
   "print": PRINT,
@@ -32,6 +33,18 @@ var keywords = map[string]TokenType{
   "var": VAR,
   "while": WHILE,
   "break": BREAK,
+  "defer": DEFER,
+  "switch": SWITCH,
+  "case": CASE,
+  "default": DEFAULT,
+  "do": DO,
+  "fallthrough": FALLTHROUGH,
+  "macro": MACRO,
+  "assert": ASSERT,
+  "requires": REQUIRES,
+  "ensures": ENSURES,
+  "const": CONST,
+  "val": VAL,
 }
 
 func NewScanner(source string) *scanner {
@@ -55,6 +68,7 @@ func ScanTokens(scanner *scanner) []Token {
     "",
     nil,
     scanner.line,
+    scanner.current,
   })
   return scanner.tokens;
 }
@@ -67,14 +81,26 @@ func scanToken(scanner *scanner) {
   case ')': addToken(scanner, RIGHT_PAREN, nil); break
   case '{': addToken(scanner, LEFT_BRACE, nil); break
   case '}': addToken(scanner, RIGHT_BRACE, nil); break
+  case '[': addToken(scanner, LEFT_BRACKET, nil); break
+  case ']': addToken(scanner, RIGHT_BRACKET, nil); break
   case ',': addToken(scanner, COMMA, nil); break
-  case '.': addToken(scanner, DOT, nil); break
+  case '.':
+    if peek(scanner) == '.' && peekNext(scanner) == '.' {
+      advance(scanner)
+      advance(scanner)
+      addToken(scanner, ELLIPSIS, nil)
+    } else {
+      addToken(scanner, DOT, nil)
+    }
+    break
   case '-': addToken(scanner, MINUS, nil); break
   case '+': addToken(scanner, PLUS, nil); break
   case ';': addToken(scanner, SEMICOLON, nil); break
   case '*': addToken(scanner, STAR, nil); break
   case '?': addToken(scanner, QUESTION, nil); break
   case ':': addToken(scanner, COLON, nil); break
+  case '@': addToken(scanner, AT, nil); break
+  case '#': addToken(scanner, HASH, nil); break
   case '!':
     addToken(scanner, ifThenElse(match(scanner, '='), BANG_EQUAL, BANG), nil)
     break
@@ -218,6 +244,7 @@ func addToken(scanner *scanner, tokenType TokenType, literal any) {
     text,
     literal,
     scanner.line,
+    scanner.start,
   })
 }
 
@@ -9,6 +9,7 @@ type Token struct {
   Lexeme string
   Literal any
   Line int
+  Offset int
 }
 
 func (token Token) String() string {
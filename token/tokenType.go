@@ -11,15 +11,20 @@ const (
   RIGHT_PAREN
   LEFT_BRACE
   RIGHT_BRACE
+  LEFT_BRACKET
+  RIGHT_BRACKET
   COMMA
   QUESTION
   COLON
   DOT
+  ELLIPSIS
   MINUS
   PLUS
   SEMICOLON
   SLASH
   STAR
+  AT
+  HASH
 
   BANG
   BANG_EQUAL
@@ -41,6 +46,7 @@ const (
   FUN
   FOR
   IF
+  IS
   NIL
   OR
   PRINT
@@ -50,8 +56,20 @@ const (
   TRUE
   VAR
   WHILE
+  CONST
+  VAL
 
   BREAK
+  DEFER
+  SWITCH
+  CASE
+  DEFAULT
+  DO
+  FALLTHROUGH
+  MACRO
+  ASSERT
+  REQUIRES
+  ENSURES
 
   EOF
 )
@@ -66,6 +84,10 @@ func (e TokenType) String() string {
     return "LEFT_BRACE"
   case RIGHT_BRACE:
     return "RIGHT_BRACE"
+  case LEFT_BRACKET:
+    return "LEFT_BRACKET"
+  case RIGHT_BRACKET:
+    return "RIGHT_BRACKET"
   case COMMA:
     return "COMMA"
   case QUESTION:
@@ -74,12 +96,18 @@ func (e TokenType) String() string {
     return "COLON"
   case DOT:
     return "DOT"
+  case ELLIPSIS:
+    return "ELLIPSIS"
   case PLUS:
     return "PLUS"
   case SLASH:
     return "SLASH"
   case STAR:
     return "STAR"
+  case AT:
+    return "AT"
+  case HASH:
+    return "HASH"
   case BANG:
     return "BANG"
   case BANG_EQUAL:
@@ -110,6 +138,8 @@ func (e TokenType) String() string {
     return "FUN"
   case IF:
     return "IF"
+  case IS:
+    return "IS"
   case OR:
     return "OR"
   case RETURN:
@@ -120,8 +150,32 @@ func (e TokenType) String() string {
     return "VAR"
   case WHILE:
     return "WHILE"
+  case CONST:
+    return "CONST"
+  case VAL:
+    return "VAL"
   case BREAK:
     return "BREAK"
+  case DEFER:
+    return "DEFER"
+  case SWITCH:
+    return "SWITCH"
+  case CASE:
+    return "CASE"
+  case DEFAULT:
+    return "DEFAULT"
+  case DO:
+    return "DO"
+  case FALLTHROUGH:
+    return "FALLTHROUGH"
+  case MACRO:
+    return "MACRO"
+  case ASSERT:
+    return "ASSERT"
+  case REQUIRES:
+    return "REQUIRES"
+  case ENSURES:
+    return "ENSURES"
   case EOF:
     return "EOF"
   default:
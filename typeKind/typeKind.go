@@ -0,0 +1,17 @@
+package typekind
+
+type TypeKind int
+
+const (
+  ANY TypeKind = iota
+  NUMBER
+  STRING
+  BOOL
+  NIL
+  LIST
+  TUPLE
+  MAP
+  FUNCTION
+  CLASS
+  INSTANCE
+)